@@ -0,0 +1,140 @@
+package mnubo
+
+import (
+	"context"
+	"iter"
+)
+
+// The interfaces below are implemented by the client helpers, so code using them can be tested with fakes,
+// like the ones of the mnubotest package.
+
+// EventsAPI is the set of Events functions, implemented by Events.
+type EventsAPI interface {
+	Send(events interface{}, options SendEventsOptions, results interface{}) error
+	SendContext(ctx context.Context, events interface{}, options SendEventsOptions, results interface{}) error
+	SendFromDevice(deviceId string, events interface{}, options SendEventsOptions, results interface{}) error
+	SendFromDeviceContext(ctx context.Context, deviceId string, events interface{}, options SendEventsOptions, results interface{}) error
+	Exists(eventIds []string, results *EntitiesExist) error
+	ExistsContext(ctx context.Context, eventIds []string, results *EntitiesExist) error
+}
+
+// ObjectsAPI is the set of Objects functions, implemented by Objects.
+type ObjectsAPI interface {
+	Create(objects interface{}, results interface{}) error
+	CreateContext(ctx context.Context, objects interface{}, results interface{}) error
+	Update(objects interface{}, results interface{}) error
+	UpdateContext(ctx context.Context, objects interface{}, results interface{}) error
+	Delete(deviceId string) error
+	DeleteContext(ctx context.Context, deviceId string) error
+	Exist(deviceIds []string, results *EntitiesExist) error
+	ExistContext(ctx context.Context, deviceIds []string, results *EntitiesExist) error
+}
+
+// OwnersAPI is the set of Owners functions, implemented by Owners.
+type OwnersAPI interface {
+	Create(owners interface{}, results interface{}) error
+	CreateContext(ctx context.Context, owners interface{}, results interface{}) error
+	Update(owners interface{}, results interface{}) error
+	UpdateContext(ctx context.Context, owners interface{}, results interface{}) error
+	UpdateOwnerPassword(username string, password string) error
+	UpdateOwnerPasswordContext(ctx context.Context, username string, password string) error
+	Delete(username string) error
+	DeleteContext(ctx context.Context, username string) error
+	Exist(usernames []string, results *EntitiesExist) error
+	ExistContext(ctx context.Context, usernames []string, results *EntitiesExist) error
+	Claim(pairs []ObjectOwnerPair, results *[]ClaimResult) error
+	ClaimContext(ctx context.Context, pairs []ObjectOwnerPair, results *[]ClaimResult) error
+	Unclaim(pairs []ObjectOwnerPair, results *[]ClaimResult) error
+	UnclaimContext(ctx context.Context, pairs []ObjectOwnerPair, results *[]ClaimResult) error
+}
+
+// SearchAPI is the set of Search functions, implemented by Search.
+type SearchAPI interface {
+	CreateBasicQuery(mql interface{}, results interface{}) error
+	CreateBasicQueryContext(ctx context.Context, mql interface{}, results interface{}) error
+	CreateBasicQueryWithString(mql string, results interface{}) error
+	CreateBasicQueryWithStringContext(ctx context.Context, mql string, results interface{}) error
+	CreateBasicQueryWithBytes(mql []byte, results interface{}) error
+	CreateBasicQueryWithBytesContext(ctx context.Context, mql []byte, results interface{}) error
+	Rows(ctx context.Context, mql interface{}) iter.Seq2[[]interface{}, error]
+	ValidateQuery(mql interface{}, results *QueryValidation) error
+	ValidateQueryContext(ctx context.Context, mql interface{}, results *QueryValidation) error
+	ValidateQueryWithString(mql string, results *QueryValidation) error
+	ValidateQueryWithStringContext(ctx context.Context, mql string, results *QueryValidation) error
+	ValidateQueryWithBytes(mql []byte, results *QueryValidation) error
+	ValidateQueryWithBytesContext(ctx context.Context, mql []byte, results *QueryValidation) error
+	GetDatasets(results *[]Dataset) error
+	GetDatasetsContext(ctx context.Context, results *[]Dataset) error
+}
+
+// ModelAPI is the set of Model functions, implemented by Model.
+type ModelAPI interface {
+	Export(results *DataModel) error
+	ExportContext(ctx context.Context, results *DataModel) error
+	GetTimeseries(results *[]Timeseries) error
+	GetTimeseriesContext(ctx context.Context, results *[]Timeseries) error
+	CreateObjectAttributes(oa []ObjectAttribute) error
+	CreateObjectAttributesContext(ctx context.Context, oa []ObjectAttribute) error
+	UpdateObjectAttribute(key string, oa ObjectAttribute) error
+	UpdateObjectAttributeContext(ctx context.Context, key string, oa ObjectAttribute) error
+	GenerateObjectAttributeDeployCode(key string, results *ChallengeCode) error
+	GenerateObjectAttributeDeployCodeContext(ctx context.Context, key string, results *ChallengeCode) error
+	ApplyObjectAttributeDeployCode(key string, cc ChallengeCode) error
+	ApplyObjectAttributeDeployCodeContext(ctx context.Context, key string, cc ChallengeCode) error
+	DeployObjectAttributeToProduction(key string) error
+	DeployObjectAttributeToProductionContext(ctx context.Context, key string) error
+	GetObjectAttributes(results *[]ObjectAttribute) error
+	GetObjectAttributesContext(ctx context.Context, results *[]ObjectAttribute) error
+	CreateTimeseries(ts []Timeseries) error
+	CreateTimeseriesContext(ctx context.Context, ts []Timeseries) error
+	UpdateTimeseries(key string, ts Timeseries) error
+	UpdateTimeseriesContext(ctx context.Context, key string, ts Timeseries) error
+	GenerateTimeseriesDeployCode(key string, results *ChallengeCode) error
+	GenerateTimeseriesDeployCodeContext(ctx context.Context, key string, results *ChallengeCode) error
+	ApplyTimeseriesDeployCode(key string, cc ChallengeCode) error
+	ApplyTimeseriesDeployCodeContext(ctx context.Context, key string, cc ChallengeCode) error
+	DeployTimeseriesToProduction(key string) error
+	DeployTimeseriesToProductionContext(ctx context.Context, key string) error
+	CreateOwnerAttributes(oa []OwnerAttribute) error
+	CreateOwnerAttributesContext(ctx context.Context, oa []OwnerAttribute) error
+	UpdateOwnerAttribute(key string, oa OwnerAttribute) error
+	UpdateOwnerAttributeContext(ctx context.Context, key string, oa OwnerAttribute) error
+	GenerateOwnerAttributeDeployCode(key string, results *ChallengeCode) error
+	GenerateOwnerAttributeDeployCodeContext(ctx context.Context, key string, results *ChallengeCode) error
+	ApplyOwnerAttributeDeployCode(key string, cc ChallengeCode) error
+	ApplyOwnerAttributeDeployCodeContext(ctx context.Context, key string, cc ChallengeCode) error
+	DeployOwnerAttributeToProduction(key string) error
+	DeployOwnerAttributeToProductionContext(ctx context.Context, key string) error
+	GetOwnerAttributes(results *[]OwnerAttribute) error
+	GetOwnerAttributesContext(ctx context.Context, results *[]OwnerAttribute) error
+	GetEventTypes(results *[]EventType) error
+	GetEventTypesContext(ctx context.Context, results *[]EventType) error
+	CreateEventTypes(et []EventType) error
+	CreateEventTypesContext(ctx context.Context, et []EventType) error
+	UpdateEventType(key string, et EventType) error
+	UpdateEventTypeContext(ctx context.Context, key string, et EventType) error
+	DeleteEventType(key string) error
+	DeleteEventTypeContext(ctx context.Context, key string) error
+	AddEventTypeRelation(typeKey string, entityKey string) error
+	AddEventTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error
+	RemoveEventTypeRelation(typeKey string, entityKey string) error
+	RemoveEventTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error
+	GetObjectTypes(results *[]ObjectType) error
+	GetObjectTypesContext(ctx context.Context, results *[]ObjectType) error
+	CreateObjectTypes(ot []ObjectType) error
+	CreateObjectTypesContext(ctx context.Context, ot []ObjectType) error
+	UpdateObjectType(key string, ot ObjectType) error
+	UpdateObjectTypeContext(ctx context.Context, key string, ot ObjectType) error
+	DeleteObjectType(key string) error
+	DeleteObjectTypeContext(ctx context.Context, key string) error
+	AddObjectTypeRelation(typeKey string, entityKey string) error
+	AddObjectTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error
+	RemoveObjectTypeRelation(typeKey string, entityKey string) error
+	RemoveObjectTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error
+	GenerateResetCode(results *ChallengeCode) error
+	GenerateResetCodeContext(ctx context.Context, results *ChallengeCode) error
+	ApplyResetCode(cc ChallengeCode) error
+	ApplyResetCodeContext(ctx context.Context, cc ChallengeCode) error
+	ResetDataModel() error
+	ResetDataModelContext(ctx context.Context) error
+}
@@ -0,0 +1,301 @@
+package mnubo
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCircuitBreakerMinRequests    = 10
+	DefaultCircuitBreakerWindow         = time.Second * 30
+	DefaultCircuitBreakerOpenTimeout    = time.Second * 30
+	DefaultCircuitBreakerHalfOpenProbes = 1
+)
+
+// ErrCircuitOpen is returned without sending the request while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open, SmartObjects is not available")
+
+// CircuitState is the state of the circuit breaker of a client.
+type CircuitState int
+
+const (
+	// CircuitClosed lets every request through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails every request with ErrCircuitOpen.
+	CircuitOpen
+	// CircuitHalfOpen lets probe requests through to check if the platform is available again.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig stops sending requests for a while when too many of them fail,
+// instead of having every caller retry during an outage. It must be configured before the first request.
+type CircuitBreakerConfig struct {
+	// FailureRate opens the circuit when the rate of failed requests during Window reaches it.
+	// The circuit breaker is disabled if it is 0.
+	FailureRate float64
+	// MinRequests is the number of requests needed during Window before the circuit can open.
+	MinRequests int
+	Window      time.Duration
+	// OpenTimeout is how long the circuit stays open before letting probe requests through.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of probe requests which must succeed to close the circuit.
+	// The circuit opens again as soon as one of them fails.
+	HalfOpenProbes int
+	// OnStateChange is called on each state transition, it will not be called if value is nil.
+	// Transitions are reported in order, by one goroutine at a time, which may not be the one causing them.
+	OnStateChange func(from CircuitState, to CircuitState)
+	// Failure replaces the default decision when set, which counts 5xx responses, network errors and
+	// timeouts as failures. statusCode is 0 when the request failed with err before getting a response.
+	// Requests cancelled by their caller are not counted, as they tell nothing about the platform.
+	Failure func(statusCode int, err error) bool
+}
+
+// DefaultCircuitBreakerConfig is disabled, set FailureRate to enable it.
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		MinRequests:    DefaultCircuitBreakerMinRequests,
+		Window:         DefaultCircuitBreakerWindow,
+		OpenTimeout:    DefaultCircuitBreakerOpenTimeout,
+		HalfOpenProbes: DefaultCircuitBreakerHalfOpenProbes,
+	}
+}
+
+// validate returns the errors of a circuit breaker configuration, which is only checked when enabled.
+func (c *CircuitBreakerConfig) validate() []error {
+	if c.FailureRate == 0 {
+		return nil
+	}
+	var errs []error
+	if c.FailureRate < 0 || c.FailureRate > 1 {
+		errs = append(errs, invalidConfig("circuit breaker failure rate must be between 0 and 1"))
+	}
+	if c.MinRequests <= 0 {
+		errs = append(errs, invalidConfig("circuit breaker minimum requests must be positive"))
+	}
+	// A zero window would reset the counters on every request.
+	if c.Window <= 0 {
+		errs = append(errs, invalidConfig("circuit breaker window must be positive"))
+	}
+	if c.OpenTimeout <= 0 {
+		errs = append(errs, invalidConfig("circuit breaker open timeout must be positive"))
+	}
+	return errs
+}
+
+// isFailure returns true if a request failing with statusCode or err counts as a failure of the platform.
+func (c *CircuitBreakerConfig) isFailure(statusCode int, err error) bool {
+	if c.Failure != nil {
+		return c.Failure(statusCode, err)
+	}
+	return err != nil || statusCode >= http.StatusInternalServerError
+}
+
+// circuitBreaker counts the failures of the requests sent by a client.
+type circuitBreaker struct {
+	mu     sync.Mutex
+	config CircuitBreakerConfig
+	state  CircuitState
+	// generation changes with the state, so requests allowed in a previous state are not counted.
+	generation  uint64
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+	// changes are the state changes to report once the mutex is released.
+	changes []stateChange
+	// notifying is true while a goroutine calls OnStateChange, the other ones leave their changes to it.
+	notifying bool
+}
+
+type stateChange struct {
+	from CircuitState
+	to   CircuitState
+}
+
+// newCircuitBreaker creates a closed circuit breaker, or returns nil if config is disabled.
+func newCircuitBreaker(config CircuitBreakerConfig) *circuitBreaker {
+	if config.FailureRate <= 0 {
+		return nil
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+	return &circuitBreaker{
+		config:      config,
+		windowStart: time.Now(),
+	}
+}
+
+// circuitBreaker returns the circuit breaker of the client, created on first use.
+func (m *Mnubo) circuitBreaker() *circuitBreaker {
+	m.circuitBreakerOnce.Do(func() {
+		m.breaker = newCircuitBreaker(m.CircuitBreaker)
+	})
+	return m.breaker
+}
+
+// CircuitState returns the state of the circuit breaker, which is always closed when it is disabled.
+func (m *Mnubo) CircuitState() CircuitState {
+	return m.circuitBreaker().currentState(time.Now())
+}
+
+// allow returns ErrCircuitOpen if a request can't be sent, or the generation to record its outcome with.
+func (cb *circuitBreaker) allow(now time.Time) (uint64, error) {
+	if cb == nil {
+		return 0, nil
+	}
+	cb.mu.Lock()
+	cb.refresh(now)
+
+	var err error
+	switch cb.state {
+	case CircuitOpen:
+		err = ErrCircuitOpen
+	case CircuitHalfOpen:
+		if cb.probes >= cb.config.HalfOpenProbes {
+			err = ErrCircuitOpen
+		} else {
+			cb.probes++
+		}
+	}
+	generation := cb.generation
+	cb.unlock()
+	return generation, err
+}
+
+// record counts the outcome of a request allowed in generation.
+func (cb *circuitBreaker) record(generation uint64, now time.Time, statusCode int, err error) {
+	if cb == nil {
+		return
+	}
+	failure := cb.config.isFailure(statusCode, err)
+
+	cb.mu.Lock()
+	cb.refresh(now)
+	if generation == cb.generation {
+		switch cb.state {
+		case CircuitClosed:
+			cb.requests++
+			if failure {
+				cb.failures++
+			}
+			if cb.requests >= cb.config.MinRequests && float64(cb.failures)/float64(cb.requests) >= cb.config.FailureRate {
+				cb.setState(CircuitOpen, now)
+			}
+		case CircuitHalfOpen:
+			if failure {
+				cb.setState(CircuitOpen, now)
+			} else if cb.successes++; cb.successes >= cb.config.HalfOpenProbes {
+				cb.setState(CircuitClosed, now)
+			}
+		}
+	}
+	cb.unlock()
+}
+
+// cancel releases a request allowed in generation without counting it, like a request cancelled by its caller.
+func (cb *circuitBreaker) cancel(generation uint64) {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	if generation == cb.generation && cb.state == CircuitHalfOpen && cb.probes > 0 {
+		cb.probes--
+	}
+	cb.unlock()
+}
+
+// currentState returns the state of the circuit breaker at now.
+func (cb *circuitBreaker) currentState(now time.Time) CircuitState {
+	if cb == nil {
+		return CircuitClosed
+	}
+	cb.mu.Lock()
+	cb.refresh(now)
+	state := cb.state
+	cb.unlock()
+	return state
+}
+
+// refresh half-opens the circuit after OpenTimeout, and starts a new window when the current one is over.
+func (cb *circuitBreaker) refresh(now time.Time) {
+	switch cb.state {
+	case CircuitOpen:
+		if now.Sub(cb.openedAt) >= cb.config.OpenTimeout {
+			cb.setState(CircuitHalfOpen, now)
+		}
+	case CircuitClosed:
+		if now.Sub(cb.windowStart) >= cb.config.Window {
+			cb.windowStart = now
+			cb.requests = 0
+			cb.failures = 0
+		}
+	}
+}
+
+func (cb *circuitBreaker) setState(state CircuitState, now time.Time) {
+	cb.changes = append(cb.changes, stateChange{from: cb.state, to: state})
+	cb.state = state
+	cb.generation++
+	cb.windowStart = now
+	cb.requests = 0
+	cb.failures = 0
+	cb.probes = 0
+	cb.successes = 0
+	if state == CircuitOpen {
+		cb.openedAt = now
+	}
+}
+
+// unlock releases the mutex, then calls OnStateChange with each state change, in order.
+// A transition through several states, like open to half-open to open, is reported as several changes.
+// The changes queued while OnStateChange runs are reported by the same goroutine, after the current ones.
+func (cb *circuitBreaker) unlock() {
+	if cb.config.OnStateChange == nil {
+		cb.changes = nil
+	}
+	if cb.notifying || len(cb.changes) == 0 {
+		cb.mu.Unlock()
+		return
+	}
+
+	cb.notifying = true
+	locked := true
+	defer func() {
+		// Let the next state change be reported if OnStateChange panics.
+		if !locked {
+			cb.mu.Lock()
+		}
+		cb.notifying = false
+		cb.mu.Unlock()
+	}()
+	for len(cb.changes) > 0 {
+		changes := cb.changes
+		cb.changes = nil
+		cb.mu.Unlock()
+		locked = false
+
+		for _, c := range changes {
+			cb.config.OnStateChange(c.from, c.to)
+		}
+		cb.mu.Lock()
+		locked = true
+	}
+}
@@ -0,0 +1,261 @@
+package mnubo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_States(t *testing.T) {
+	var transitions []string
+	config := DefaultCircuitBreakerConfig()
+	config.FailureRate = 0.5
+	config.MinRequests = 4
+	config.HalfOpenProbes = 2
+	config.OnStateChange = func(from CircuitState, to CircuitState) {
+		transitions = append(transitions, fmt.Sprintf("%s -> %s", from, to))
+	}
+	cb := newCircuitBreaker(config)
+	now := time.Now()
+
+	send := func(at time.Duration, statusCode int) error {
+		generation, err := cb.allow(now.Add(at))
+		if err == nil {
+			cb.record(generation, now.Add(at), statusCode, nil)
+		}
+		return err
+	}
+
+	cases := []struct {
+		At         time.Duration
+		StatusCode int
+		Expected   error
+		State      CircuitState
+	}{
+		{0, http.StatusServiceUnavailable, nil, CircuitClosed},
+		{0, http.StatusOK, nil, CircuitClosed},
+		{0, http.StatusNotFound, nil, CircuitClosed},
+		{0, http.StatusBadGateway, nil, CircuitOpen},
+		{time.Second, http.StatusOK, ErrCircuitOpen, CircuitOpen},
+		{config.OpenTimeout, http.StatusServiceUnavailable, nil, CircuitOpen},
+		{config.OpenTimeout + time.Second, http.StatusOK, ErrCircuitOpen, CircuitOpen},
+		{2 * config.OpenTimeout, http.StatusOK, nil, CircuitHalfOpen},
+		{2 * config.OpenTimeout, http.StatusOK, nil, CircuitClosed},
+		{2 * config.OpenTimeout, http.StatusInternalServerError, nil, CircuitClosed},
+	}
+	for i, c := range cases {
+		if err := send(c.At, c.StatusCode); err != c.Expected {
+			t.Errorf("%d, expecting: %v, got: %v", i, c.Expected, err)
+		}
+		if state := cb.currentState(now.Add(c.At)); state != c.State {
+			t.Errorf("%d, expecting state: %s, got: %s", i, c.State, state)
+		}
+	}
+
+	expected := []string{"closed -> open", "open -> half-open", "half-open -> open", "open -> half-open", "half-open -> closed"}
+	if len(transitions) != len(expected) {
+		t.Fatalf("expecting transitions: %+v, got: %+v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Errorf("%d, expecting: %s, got: %s", i, expected[i], transitions[i])
+		}
+	}
+}
+
+func TestCircuitBreaker_StateChangeOrder(t *testing.T) {
+	var changes []string
+	entered, release := make(chan struct{}), make(chan struct{})
+	config := DefaultCircuitBreakerConfig()
+	config.FailureRate = 0.5
+	config.MinRequests = 1
+	config.OnStateChange = func(from CircuitState, to CircuitState) {
+		if len(changes) == 0 {
+			// Let another goroutine change the state while the first change is being reported.
+			close(entered)
+			<-release
+		}
+		changes = append(changes, fmt.Sprintf("%s -> %s", from, to))
+	}
+	cb := newCircuitBreaker(config)
+	now := time.Now()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		generation, _ := cb.allow(now)
+		cb.record(generation, now, http.StatusServiceUnavailable, nil)
+	}()
+	<-entered
+	if state := cb.currentState(now.Add(config.OpenTimeout)); state != CircuitHalfOpen {
+		t.Errorf("expecting the circuit to half-open, got: %s", state)
+	}
+	close(release)
+	<-done
+
+	expected := []string{"closed -> open", "open -> half-open"}
+	if fmt.Sprint(changes) != fmt.Sprint(expected) {
+		t.Errorf("expecting changes: %+v, got: %+v", expected, changes)
+	}
+}
+
+func TestCircuitBreaker_HalfOpenProbes(t *testing.T) {
+	config := DefaultCircuitBreakerConfig()
+	config.FailureRate = 1
+	config.MinRequests = 1
+	cb := newCircuitBreaker(config)
+	now := time.Now()
+
+	generation, _ := cb.allow(now)
+	cb.record(generation, now, 0, errors.New("connection refused"))
+
+	now = now.Add(config.OpenTimeout)
+	probe, err := cb.allow(now)
+	if err != nil {
+		t.Fatalf("expecting a probe request, got: %+v", err)
+	}
+	if _, err := cb.allow(now); err != ErrCircuitOpen {
+		t.Errorf("expecting other requests to fail while probing, got: %+v", err)
+	}
+
+	// A request allowed before the circuit opened does not count as a probe.
+	cb.record(generation, now, http.StatusOK, nil)
+	if state := cb.currentState(now); state != CircuitHalfOpen {
+		t.Errorf("expecting half-open state, got: %s", state)
+	}
+
+	cb.record(probe, now, http.StatusOK, nil)
+	if state := cb.currentState(now); state != CircuitClosed {
+		t.Errorf("expecting closed state, got: %s", state)
+	}
+}
+
+func TestCircuitBreaker_Cancel(t *testing.T) {
+	config := DefaultCircuitBreakerConfig()
+	config.FailureRate = 1
+	config.MinRequests = 1
+	cb := newCircuitBreaker(config)
+	now := time.Now()
+
+	// A cancelled request is not counted.
+	generation, _ := cb.allow(now)
+	cb.cancel(generation)
+	if state := cb.currentState(now); state != CircuitClosed {
+		t.Errorf("expecting closed state, got: %s", state)
+	}
+
+	generation, _ = cb.allow(now)
+	cb.record(generation, now, 0, context.DeadlineExceeded)
+	if state := cb.currentState(now); state != CircuitOpen {
+		t.Errorf("expecting timeouts to open the circuit, got: %s", state)
+	}
+
+	// A cancelled probe gives its slot back.
+	now = now.Add(config.OpenTimeout)
+	probe, _ := cb.allow(now)
+	cb.cancel(probe)
+	if _, err := cb.allow(now); err != nil {
+		t.Errorf("expecting another probe request, got: %+v", err)
+	}
+}
+
+func TestCircuitBreaker_ClientTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.RetryPolicy.MaxAttempts = 1
+	m.CircuitBreaker.FailureRate = 1
+	m.CircuitBreaker.MinRequests = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var results []Dataset
+	if err := m.Search.GetDatasetsContext(ctx, &results); err == nil {
+		t.Fatalf("expecting the request to be cancelled")
+	}
+	if m.CircuitState() != CircuitClosed {
+		t.Errorf("expecting requests cancelled by the caller not to count, got: %s", m.CircuitState())
+	}
+
+	m.Timeout = 50 * time.Millisecond
+	if err := m.Search.GetDatasets(&results); err == nil {
+		t.Fatalf("expecting the request to time out")
+	}
+	if m.CircuitState() != CircuitOpen {
+		t.Errorf("expecting timeouts to count as failures, got: %s", m.CircuitState())
+	}
+}
+
+func TestCircuitBreaker_Client(t *testing.T) {
+	var requests, healthy int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.ExponentialBackoff.MaxElapsedTime = time.Minute
+	m.RetryPolicy.Jitter = 0
+	m.CircuitBreaker.FailureRate = 0.5
+	m.CircuitBreaker.MinRequests = 2
+	// The third attempt is sent 1.25s after the first one, while the circuit is still open.
+	m.CircuitBreaker.OpenTimeout = time.Second
+
+	var transitions []CircuitState
+	m.CircuitBreaker.OnStateChange = func(from CircuitState, to CircuitState) {
+		transitions = append(transitions, to)
+	}
+
+	var results []Dataset
+	start := time.Now()
+	err := m.Search.GetDatasets(&results)
+
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expecting retries to stop once the circuit opens, got: %+v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expecting to fail fast, took: %s", elapsed)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("expecting 2 requests before the circuit opens, got: %d", n)
+	}
+	if m.CircuitState() != CircuitOpen {
+		t.Errorf("expecting open circuit, got: %s", m.CircuitState())
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(m.CircuitBreaker.OpenTimeout)
+
+	if err := m.Search.GetDatasets(&results); err != nil {
+		t.Errorf("expecting probe request to succeed, got: %+v", err)
+	}
+	if m.CircuitState() != CircuitClosed {
+		t.Errorf("expecting closed circuit, got: %s", m.CircuitState())
+	}
+	expected := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if fmt.Sprint(transitions) != fmt.Sprint(expected) {
+		t.Errorf("expecting transitions: %v, got: %v", expected, transitions)
+	}
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	m := NewClientWithToken("TOKEN", "")
+	if m.circuitBreaker() != nil || m.CircuitState() != CircuitClosed {
+		t.Errorf("expecting circuit breaker to be disabled by default")
+	}
+}
@@ -2,17 +2,16 @@ package mnubo
 
 import (
 	"bytes"
-	"compress/gzip"
+	"context"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/cenkalti/backoff"
-	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,12 +19,25 @@ const (
 	DefaultTimeout = time.Second * 10
 
 	DefaultBackoffMaxInterval = time.Minute * 5
+
+	// DefaultMaxIdleConnsPerHost is the number of keep-alive connections kept open to SmartObjects.
+	DefaultMaxIdleConnsPerHost = 100
 )
 
 // CompressionConfig is used to compress requests and / or response to / from the SmartObjects platform.
+// Payloads are compressed with gzip at gzip.BestSpeed by default.
 type CompressionConfig struct {
 	Request  bool
 	Response bool
+	// Encoding of the requests: gzip, deflate or zstd. zstd is only used once SmartObjects advertises it
+	// in an Accept-Encoding response header, gzip is used until then.
+	// Responses are requested in this encoding, or gzip, and decoded according to their Content-Encoding.
+	Encoding string
+	// Level of compression of Encoding, 0 is the default: gzip.BestSpeed for gzip and deflate,
+	// zstd.SpeedDefault for zstd. Levels go from 1 (fastest) to 9 for gzip and deflate, and to 22 for zstd.
+	Level int
+	// MinSize is the size in bytes under which payloads are sent uncompressed.
+	MinSize int
 }
 
 // ExponentialBackoffConfig is used to configure exponential backoff.
@@ -34,31 +46,56 @@ type ExponentialBackoffConfig struct {
 	// After MaxElapsedTime the ExponentialBackOff stops.
 	// It never stops if MaxElapsedTime == 0.
 	MaxElapsedTime time.Duration
-	// Callback called between retries when the platform is not available.
+	// Callback called between retries, see RetryPolicy for the failures being retried.
+	// The error is an *AttemptError giving the number of the failed attempt.
 	// It will not be called if value is nil.
 	NotifyOnError func(error, time.Duration)
 }
 
 // Mnubo is the main object representing all available endpoints in SmartObjects.
 type Mnubo struct {
-	ClientId           string
-	ClientSecret       string
-	ClientToken        string
-	Host               string
+	ClientId     string
+	ClientSecret string
+	ClientToken  string
+	Host         string
+	// Deprecated: AccessToken is neither read nor updated by the client, which caches its tokens per scope
+	// and refreshes them concurrently. Use CachedAccessToken to read the token in use, and WithToken or
+	// WithTokenSource to provide one.
 	AccessToken        AccessToken
 	Timeout            time.Duration // Timeout for HTTP requests sent to SmartObjects.
 	Compression        CompressionConfig
 	ExponentialBackoff ExponentialBackoffConfig
+	RetryPolicy        RetryPolicy
+	RateLimit          RateLimitConfig
+	CircuitBreaker     CircuitBreakerConfig
 	Model              *Model
 	Events             *Events
 	Objects            *Objects
 	Owners             *Owners
 	Search             *Search
-	CustomTransport    *http.Transport
+	CustomTransport    *http.Transport // Replaces the transport of HttpClient when set.
+	HttpClient         *http.Client    // Sends requests reusing connections, can use any http.RoundTripper.
+	Middlewares        []Middleware    // Called around each request, the first one being the outermost.
+	Observers          []Observer      // Notified of the calls, their HTTP requests and the token refreshes.
+	Logger             *slog.Logger    // Logs requests at debug level and failures at warn level when set.
+	LogConfig          LogConfig       // What is logged with Logger, and the secrets redacted.
+	Scopes             Scopes          // Scopes of the tokens obtained with client id / secret, per group of endpoints.
+	TokenCache         *FileTokenCache // Persists the tokens obtained with client id / secret between processes when set.
+	tokens             *tokenCache
+	scopesMu           sync.Mutex
+	scopeTokens        map[string]*tokenCache
+	tokenSource        TokenSource
+	advertised         advertisedEncodings
+	rateLimiterOnce    sync.Once
+	limiter            *rateLimiter
+	circuitBreakerOnce sync.Once
+	breaker            *circuitBreaker
 }
 
 // ClientRequest is an internal structure to help with making HTTP requests to SmartObjects.
 type ClientRequest struct {
+	operation       string
+	events          int
 	authorization   string
 	method          string
 	path            string
@@ -66,6 +103,18 @@ type ClientRequest struct {
 	urlQuery        url.Values
 	payload         []byte
 	skipCompression bool
+	header          http.Header
+	// idempotent marks POST requests which can be sent again, like queries.
+	idempotent bool
+}
+
+// isIdempotent returns true if sending the request more than once has the same effect as sending it once.
+func (cr *ClientRequest) isIdempotent() bool {
+	switch cr.method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return cr.idempotent
 }
 
 // AccessToken represents a token obtained after validating client id and secret.
@@ -79,14 +128,22 @@ type AccessToken struct {
 }
 
 type smartObjectsNotAvailableError struct {
+	apiError *APIError
 }
 
 func (e *smartObjectsNotAvailableError) Error() string {
 	return "SmartObjects platform is not available"
 }
 
-func smartObjectsNotAvailable() *smartObjectsNotAvailableError {
-	return &smartObjectsNotAvailableError{}
+// Unwrap gives access to the APIError of the last attempt.
+func (e *smartObjectsNotAvailableError) Unwrap() error {
+	return e.apiError
+}
+
+func smartObjectsNotAvailable(apiError *APIError) *smartObjectsNotAvailableError {
+	return &smartObjectsNotAvailableError{
+		apiError: apiError,
+	}
 }
 
 // hasExpired returns true if an access token has expired.
@@ -100,7 +157,7 @@ func NewClient(id string, secret string, host string) *Mnubo {
 	m := &Mnubo{
 		ClientId:     id,
 		ClientSecret: secret,
-		Host:         host,
+		Host:         normalizeHost(host),
 	}
 	m.initClient()
 	return m
@@ -110,12 +167,33 @@ func NewClient(id string, secret string, host string) *Mnubo {
 func NewClientWithToken(token string, host string) *Mnubo {
 	m := &Mnubo{
 		ClientToken: token,
-		Host:        host,
+		Host:        normalizeHost(host),
+	}
+	m.initClient()
+	return m
+}
+
+// NewClientWithTokenSource creates a new Mnubo structure getting its access tokens from ts.
+// See StaticTokenSource, NewClientCredentialsTokenSource, NewEnvTokenSource, NewFileTokenSource and ChainTokenSources.
+func NewClientWithTokenSource(ts TokenSource, host string) *Mnubo {
+	m := &Mnubo{
+		Host: normalizeHost(host),
 	}
 	m.initClient()
+	bindTokenSource(ts, m)
+	m.tokenSource = ts
 	return m
 }
 
+// normalizeHost returns the normalized host, or host itself if it is not valid.
+// Constructors which can't return errors send requests to invalid hosts as given, and fail then.
+func normalizeHost(host string) string {
+	if normalized, err := NormalizeHost(host); err == nil {
+		return normalized
+	}
+	return host
+}
+
 // initClient initializes internal wrappers for SmartObjects main endpoints.
 func (m *Mnubo) initClient() {
 	m.Model = NewModel(m)
@@ -124,9 +202,45 @@ func (m *Mnubo) initClient() {
 	m.Owners = NewOwners(m)
 	m.Search = NewSearch(m)
 	m.Timeout = DefaultTimeout
+	m.HttpClient = &http.Client{
+		Transport: newTransport(),
+	}
 	m.ExponentialBackoff = ExponentialBackoffConfig{
 		MaxElapsedTime: DefaultBackoffMaxInterval,
 	}
+	m.RetryPolicy = DefaultRetryPolicy()
+	m.RateLimit = DefaultRateLimitConfig()
+	m.CircuitBreaker = DefaultCircuitBreakerConfig()
+	m.LogConfig = DefaultLogConfig()
+	m.Scopes = Scopes{Default: ScopeAll}
+	m.tokens = m.newScopeTokenCache("")
+	m.tokenSource = m.tokens
+}
+
+// newTransport creates the transport of the HTTP client used by default.
+// It keeps more idle connections than http.DefaultTransport, which only keeps 2 per host
+// and would open new connections when several goroutines share the client.
+func newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = DefaultMaxIdleConnsPerHost
+	t.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	return t
+}
+
+// httpClient returns the HTTP client to use for a request, applying Timeout and CustomTransport.
+// The returned client is a shallow copy sharing the transport, and its connections, of HttpClient.
+func (m *Mnubo) httpClient() *http.Client {
+	client := http.Client{}
+	if m.HttpClient != nil {
+		client = *m.HttpClient
+	}
+	if m.Timeout > 0 {
+		client.Timeout = m.Timeout
+	}
+	if m.CustomTransport != nil {
+		client.Transport = m.CustomTransport
+	}
+	return &client
 }
 
 // isUsingStaticToken returns true if the client was initialized with its own static token
@@ -137,15 +251,50 @@ func (m *Mnubo) isUsingStaticToken() bool {
 
 // GetAccessToken fetches a new AccessToken with scope ALL.
 func (m *Mnubo) GetAccessToken() (AccessToken, error) {
-	return m.GetAccessTokenWithScope("ALL")
+	return m.GetAccessTokenContext(context.Background())
+}
+
+// GetAccessTokenContext is like GetAccessToken but uses ctx to cancel the request and its retries.
+func (m *Mnubo) GetAccessTokenContext(ctx context.Context) (AccessToken, error) {
+	return m.GetAccessTokenWithScopeContext(ctx, "ALL")
 }
 
 // GetAccessTokenWithScope fetches a new AccessToken with specified scope.
 func (m *Mnubo) GetAccessTokenWithScope(scope string) (AccessToken, error) {
+	return m.GetAccessTokenWithScopeContext(context.Background(), scope)
+}
+
+// GetAccessTokenWithScopeContext is like GetAccessTokenWithScope but uses ctx to cancel the request and its retries.
+// The new token replaces the one used by the client for the following requests needing this scope, see Scopes.
+func (m *Mnubo) GetAccessTokenWithScopeContext(ctx context.Context, scope string) (AccessToken, error) {
+	at, err := m.fetchAccessToken(ctx, m.ClientId, m.ClientSecret, scope)
+	if err != nil {
+		return at, err
+	}
+	m.scopeTokenCache(scope).store(at)
+	return at, nil
+}
+
+// CachedAccessToken returns the token obtained with client id / secret used by the following requests needing
+// scope, or the default scope if scope is empty. The token is empty if none was obtained yet, or if the client
+// uses a static token or a TokenSource.
+func (m *Mnubo) CachedAccessToken(scope string) AccessToken {
+	if m.tokenSource != TokenSource(m.tokens) {
+		return AccessToken{}
+	}
+	if scope == "" {
+		scope = m.Scopes.defaultScope()
+	}
+	return m.scopeTokenCache(scope).cached()
+}
+
+// fetchAccessToken requests a new AccessToken from the platform without caching it.
+func (m *Mnubo) fetchAccessToken(ctx context.Context, id string, secret string, scope string) (AccessToken, error) {
 	payload := fmt.Sprintf("grant_type=client_credentials&scope=%s", scope)
-	data := []byte(fmt.Sprintf("%s:%s", m.ClientId, m.ClientSecret))
+	data := []byte(fmt.Sprintf("%s:%s", id, secret))
 
 	cr := ClientRequest{
+		operation:       "Mnubo.GetAccessToken",
 		authorization:   fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString(data)),
 		method:          "POST",
 		path:            "/oauth/token",
@@ -153,120 +302,160 @@ func (m *Mnubo) GetAccessTokenWithScope(scope string) (AccessToken, error) {
 		skipCompression: true,
 		payload:         []byte(payload),
 	}
+	ctx, op := m.startOperation(ctx, &cr)
 	at := AccessToken{}
-	err := m.doRequest(cr, &at)
+	err := m.doRequest(ctx, cr, &at)
 	now := time.Now()
+	op.end(err)
+	m.observeTokenRefresh(err)
 
-	if err == nil {
-		if err != nil {
-			return at, fmt.Errorf("unable to unmarshall body %t", err)
-		}
-		dur, err := time.ParseDuration(fmt.Sprintf("%dms", at.ExpiresIn))
-		at.ExpiresAt = now.Add(dur)
-		m.AccessToken = at
+	if err != nil {
 		return at, err
 	}
+	dur, err := time.ParseDuration(fmt.Sprintf("%dms", at.ExpiresIn))
+	at.ExpiresAt = now.Add(dur)
 	return at, err
 }
 
-// doGzip compressed data using gzip BestSpeed algorithm.
-func doGzip(w io.Writer, data []byte) error {
-	gw, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
-	if err != nil {
-		return err
-	}
-	if _, err := gw.Write(data); err != nil {
-		return err
-	}
-	if err := gw.Flush(); err != nil {
-		return err
-	}
-	if err := gw.Close(); err != nil {
-		return err
-	}
-	return nil
-}
-
-// doGunzip uncompressed gzipped data.
-func doGunzip(w io.Writer, data []byte) error {
-	gr, err := gzip.NewReader(bytes.NewBuffer(data))
-	defer gr.Close()
-	if err != nil {
-		return err
-	}
-	ud, err := ioutil.ReadAll(gr)
-	if err != nil {
-		return err
-	}
-	w.Write(ud)
-	return nil
-}
+// doHttpRequest returns the operation sending req, retried by b.
+// Failures are retried or not according to the RetryPolicy of b.
+// req must have a GetBody function when it has a body, so each attempt sends the whole payload.
+// Each attempt is traced as a child of the operation started in the request context, if any.
+// Each attempt waits for its turn according to limits, which adapt to the throttling responses,
+// and fails with ErrCircuitOpen without being sent while the circuit breaker is open.
+func doHttpRequest(client *http.Client, req *http.Request, b *retryBackOff, limits requestLimiter, breaker *circuitBreaker, advertised *advertisedEncodings, response interface{}) func() error {
+	op := operationFromContext(req.Context())
 
-func doHttpRequest(client *http.Client, req *http.Request, response interface{}) func() error {
 	wrappedFunc := func() error {
-		res, err := client.Do(req)
-		if err != nil {
+		if err := limits.wait(req.Context()); err != nil {
 			return backoff.Permanent(err)
 		}
-		defer res.Body.Close()
-
-		var body []byte
-		body, err = ioutil.ReadAll(res.Body)
-
+		generation, err := breaker.allow(time.Now())
 		if err != nil {
 			return backoff.Permanent(err)
 		}
+		// A request cancelled by its caller tells nothing about the platform, unlike the client Timeout.
+		record := func(statusCode int, err error) {
+			if err != nil && req.Context().Err() != nil {
+				breaker.cancel(generation)
+				return
+			}
+			breaker.record(generation, time.Now(), statusCode, err)
+		}
 
-		if res.Header.Get("Content-Encoding") == "gzip" {
-			var w bytes.Buffer
-			err := doGunzip(&w, body)
+		b.attempt++
+		b.retryAfter = 0
 
+		ctx, endAttempt := op.startAttempt(req.Context(), req, b.attempt)
+
+		// The body of the previous attempt has been consumed, send a fresh copy of the payload.
+		attempt := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
 			if err != nil {
+				breaker.cancel(generation)
+				endAttempt(nil, err)
 				return backoff.Permanent(err)
 			}
+			attempt.Body = body
+		}
 
-			body = w.Bytes()
+		res, err := client.Do(attempt)
+		if err != nil {
+			record(0, err)
+			endAttempt(nil, err)
+			if b.isRetryableError(err) {
+				return err
+			}
+			return backoff.Permanent(err)
 		}
+		defer res.Body.Close()
+
+		limits.adapt(res)
+		advertised.observe(res)
+
+		if res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices {
+			err := decodeResponse(res, response)
+			// Only failures to receive the response count for the attempt, not invalid results.
+			record(res.StatusCode, readError(err))
+			endAttempt(res, readError(err))
 
-		ct := res.Header.Get("Content-Type")
-		if strings.Contains(ct, "application/json") {
-			err := json.Unmarshal(body, response)
 			if err != nil {
+				if isRetryableReadError(err, response, b) {
+					return err
+				}
 				return backoff.Permanent(err)
 			}
-		} else if strings.Contains(ct, "text/plain") {
-			response = string(body)
+			return nil
 		}
 
-		if res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices {
-			return nil
-		} else if res.StatusCode == http.StatusServiceUnavailable {
-			return smartObjectsNotAvailable()
+		var body []byte
+		body, err = ioutil.ReadAll(res.Body)
+		record(res.StatusCode, err)
+		endAttempt(res, err)
+
+		if err != nil {
+			if b.isRetryableError(err) {
+				return err
+			}
+			return backoff.Permanent(err)
 		}
 
-		return backoff.Permanent(errors.New(fmt.Sprintf("The server responded with StatusCode: %d - Body: %s", res.StatusCode, response)))
+		body, err = decompress(res.Header.Get("Content-Encoding"), body)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+
+		setRawResponse(response, res, body)
+		apiError := newAPIError(res, body)
+		if !b.policy.isRetryable(res.StatusCode, nil) {
+			return backoff.Permanent(apiError)
+		}
+
+		b.retryAfter = parseRetryAfter(res.Header.Get("Retry-After"))
+		if res.StatusCode == http.StatusServiceUnavailable {
+			return smartObjectsNotAvailable(apiError)
+		}
+		return apiError
 	}
 
 	return wrappedFunc
 }
 
 // doRequest is the main internal helper to send request to the SmartObjects platform.
-// It handles compression / decompression based on client configuration.
-func (m *Mnubo) doRequest(cr ClientRequest, response interface{}) error {
-	var payload []byte
+// The request goes through the client middlewares before being sent.
+func (m *Mnubo) doRequest(ctx context.Context, cr ClientRequest, response interface{}) error {
+	h := Handler(m.sendRequest)
+	for i := len(m.Middlewares) - 1; i >= 0; i-- {
+		h = m.Middlewares[i](h)
+	}
+	return h(ctx, &cr, response)
+}
 
-	if m.Compression.Request && !cr.skipCompression {
-		var w bytes.Buffer
-		err := doGzip(&w, cr.payload)
+// sendRequest sends a request to the SmartObjects platform, retrying it according to the RetryPolicy.
+// It handles compression / decompression based on client configuration.
+// Cancelling ctx aborts both the in-flight HTTP request and the backoff loop.
+func (m *Mnubo) sendRequest(ctx context.Context, cr *ClientRequest, response interface{}) error {
+	payload := cr.payload
+	var encoding string
+	if !cr.skipCompression {
+		encoding = m.Compression.requestEncoding(cr.payload, &m.advertised)
+	}
+	if encoding != "" {
+		var err error
+		payload, err = compress(encoding, m.Compression.Level, cr.payload)
 		if err != nil {
-			return fmt.Errorf("unable to gzip request: %t", err)
+			return fmt.Errorf("unable to compress request with %s: %w", encoding, err)
 		}
-		payload = w.Bytes()
-	} else {
-		payload = cr.payload
+	}
+	if len(cr.payload) > 0 {
+		operationFromContext(ctx).recordPayload(cr.payload, len(payload))
 	}
 
-	req, err := http.NewRequest(cr.method, m.Host+cr.path, bytes.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, cr.method, m.Host+cr.path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
 
 	req.Header.Add("Content-Type", cr.contentType)
 	req.Header.Add("X-MNUBO-SDK", "Go")
@@ -279,46 +468,68 @@ func (m *Mnubo) doRequest(cr ClientRequest, response interface{}) error {
 		req.URL.RawQuery = cr.urlQuery.Encode()
 	}
 
-	if m.Compression.Request {
-		req.Header.Add("Content-Encoding", "gzip")
+	if encoding != "" {
+		req.Header.Add("Content-Encoding", encoding)
 	}
 
 	if m.Compression.Response {
-		req.Header.Add("Accept-Encoding", "gzip")
+		accept, err := m.Compression.acceptEncoding()
+		if err != nil {
+			return err
+		}
+		req.Header.Add("Accept-Encoding", accept)
 	}
 
-	if err != nil {
-		return err
+	for k, v := range cr.header {
+		if _, ok := req.Header[k]; !ok {
+			req.Header[k] = v
+		}
 	}
 
-	client := &http.Client{
-		Timeout: m.Timeout,
-	}
-	if m.CustomTransport != nil {
-		client.Transport = m.CustomTransport
-	}
+	b := newRetryBackOff(ctx, m.ExponentialBackoff, m.RetryPolicy)
+	b.idempotent = cr.isIdempotent()
+	limits := m.rateLimiter().buckets(cr.operation)
+	err = backoff.RetryNotify(doHttpRequest(m.httpClient(), req, b, limits, m.circuitBreaker(), &m.advertised, response), b, b.notify(m.ExponentialBackoff.NotifyOnError))
 
-	b := backoff.NewExponentialBackOff()
-	b.MaxElapsedTime = m.ExponentialBackoff.MaxElapsedTime
-	return backoff.RetryNotify(doHttpRequest(client, req, response), b, m.ExponentialBackoff.NotifyOnError)
+	// The backoff loop gives up with the last attempt error when ctx is done,
+	// report the cancellation instead so callers can check for it with errors.Is.
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
 }
 
 // doRequestWithAuthentication is the main helper to make requests requiring authentication.
-func (m *Mnubo) doRequestWithAuthentication(cr ClientRequest, response interface{}) error {
+// When the platform rejects a cached access token before its expiration, the token is
+// discarded and the request is sent once more with a new one.
+func (m *Mnubo) doRequestWithAuthentication(ctx context.Context, cr ClientRequest, response interface{}) (err error) {
+	ctx, op := m.startOperation(ctx, &cr)
+	defer func() { op.end(err) }()
+
 	if m.isUsingStaticToken() {
 		cr.authorization = fmt.Sprintf("Bearer %s", m.ClientToken)
-	} else {
-		if m.AccessToken.hasExpired() {
-			_, err := m.GetAccessToken()
+		return m.doRequest(ctx, cr, response)
+	}
 
-			if err != nil {
-				return err
-			}
-		}
-		cr.authorization = fmt.Sprintf("Bearer %s", m.AccessToken.Value)
+	ts := m.operationTokenSource(cr.operation)
+	at, err := ts.Token(ctx)
+	if err != nil {
+		return err
 	}
+	cr.authorization = fmt.Sprintf("Bearer %s", at.Value)
+
+	err = m.doRequest(ctx, cr, response)
 
-	err := m.doRequest(cr, response)
+	var apiError *APIError
+	if errors.As(err, &apiError) && apiError.isTokenRejected() && invalidateToken(ts, at) {
+		at, err = ts.Token(ctx)
+		if err != nil {
+			return err
+		}
+		cr.authorization = fmt.Sprintf("Bearer %s", at.Value)
+
+		err = m.doRequest(ctx, cr, response)
+	}
 
 	return err
 }
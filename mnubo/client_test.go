@@ -2,11 +2,17 @@ package mnubo
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -42,16 +48,18 @@ func TestAccessToken(t *testing.T) {
 	at, _ := m.GetAccessToken()
 	now := time.Now()
 
-	if m.AccessToken.hasExpired() == true {
+	cached := m.CachedAccessToken("")
+	if cached.hasExpired() == true {
 		t.Errorf("access token should not expire so soon")
 	}
 
-	firstTokenValue := m.AccessToken.Value
+	firstTokenValue := m.CachedAccessToken("").Value
 	eat := at
 	eat.ExpiresAt = now
-	m.AccessToken = eat
+	m.tokens.store(eat)
 
-	if m.AccessToken.hasExpired() == false {
+	cached = m.CachedAccessToken("")
+	if cached.hasExpired() == false {
 		t.Errorf("access token should expire after a while")
 	}
 
@@ -61,8 +69,8 @@ func TestAccessToken(t *testing.T) {
 		path:   "test",
 	}
 
-	m.doRequestWithAuthentication(cr, &results)
-	secondTokenValue := m.AccessToken.Value
+	m.doRequestWithAuthentication(context.Background(), cr, &results)
+	secondTokenValue := m.CachedAccessToken("").Value
 
 	if firstTokenValue == secondTokenValue {
 		t.Errorf("authentication should re-fetch token after expiration")
@@ -99,6 +107,7 @@ func TestClientCustomTransport(t *testing.T) {
 		TLSHandshakeTimeout: time.Nanosecond,
 	}
 	m.CustomTransport = customTransport
+	m.RetryPolicy.MaxAttempts = 1
 
 	_, err := m.GetAccessToken()
 	if err == nil {
@@ -161,3 +170,245 @@ func TestClientErrors(t *testing.T) {
 		t.Errorf("expected: '%s', got: '%s'", expect, got)
 	}
 }
+
+func TestClientContextCancelsBackoff(t *testing.T) {
+	m := NewClient("id", "secret", "")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	m.Host = ts.URL
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+
+	start := time.Now()
+	_, err := m.GetAccessTokenContext(ctx)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expecting deadline exceeded error, got: %+v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second*2 {
+		t.Errorf("backoff loop should stop with the context, took %s", elapsed)
+	}
+}
+
+func TestClientContextCancelsInFlightRequest(t *testing.T) {
+	m := NewClientWithToken("TOKEN", "")
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+	m.Host = ts.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(time.Millisecond * 100)
+		cancel()
+	}()
+
+	var results SearchResults
+	err := m.Search.CreateBasicQueryWithStringContext(ctx, `{ "from": "event", "select": [ { "count": "*" } ] }`, &results)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expecting canceled error, got: %+v", err)
+	}
+}
+
+func TestClientReauthenticatesOnUnauthorized(t *testing.T) {
+	cases := []struct {
+		Name             string
+		Rejected         func(token string) bool
+		StaticToken      bool
+		ExpectError      bool
+		ExpectedRequests int32
+		ExpectedTokens   int32
+	}{
+		{
+			Name:             "revoked token is replaced",
+			Rejected:         func(token string) bool { return token == "Bearer token-1" },
+			ExpectError:      false,
+			ExpectedRequests: 2,
+			ExpectedTokens:   2,
+		},
+		{
+			Name:             "request is replayed only once",
+			Rejected:         func(token string) bool { return true },
+			ExpectError:      true,
+			ExpectedRequests: 2,
+			ExpectedTokens:   2,
+		},
+		{
+			Name:             "static token is not replayed",
+			Rejected:         func(token string) bool { return true },
+			StaticToken:      true,
+			ExpectError:      true,
+			ExpectedRequests: 1,
+			ExpectedTokens:   0,
+		},
+	}
+
+	for _, c := range cases {
+		var requests, tokens int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Path == "/oauth/token" {
+				n := atomic.AddInt32(&tokens, 1)
+				fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600000}`, n)
+				return
+			}
+			atomic.AddInt32(&requests, 1)
+			if body, _ := ioutil.ReadAll(r.Body); string(body) != `[{"username":"owner"}]` {
+				t.Errorf("%s, replayed request body should be identical, got: %s", c.Name, body)
+			}
+			if c.Rejected(r.Header.Get("Authorization")) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"invalid_token","error_description":"Invalid access token"}`))
+				return
+			}
+			w.Write([]byte(`{}`))
+		}))
+
+		m := NewClient("id", "secret", ts.URL)
+		if c.StaticToken {
+			m = NewClientWithToken("TOKEN", ts.URL)
+		}
+		var results interface{}
+		err := m.Owners.Update([]SimpleOwner{{Username: "owner"}}, &results)
+		ts.Close()
+
+		if (err != nil) != c.ExpectError {
+			t.Errorf("%s, expecting error: %t, got: %+v", c.Name, c.ExpectError, err)
+		}
+		if requests != c.ExpectedRequests {
+			t.Errorf("%s, expecting %d requests, got %d", c.Name, c.ExpectedRequests, requests)
+		}
+		if tokens != c.ExpectedTokens {
+			t.Errorf("%s, expecting %d token requests, got %d", c.Name, c.ExpectedTokens, tokens)
+		}
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// newDatasetsServer starts a server answering every request with an empty list of datasets,
+// counting the connections opened by clients.
+func newDatasetsServer(connections *int32) *httptest.Server {
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	ts.Config.ConnState = func(c net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			atomic.AddInt32(connections, 1)
+		}
+	}
+	ts.Start()
+	return ts
+}
+
+func TestClientReusesConnections(t *testing.T) {
+	var connections int32
+	ts := newDatasetsServer(&connections)
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				var results []Dataset
+				if err := m.Search.GetDatasets(&results); err != nil {
+					t.Errorf("client call failed: %+v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if connections > 10 {
+		t.Errorf("expecting at most one connection per goroutine, got %d", connections)
+	}
+}
+
+func TestClientCustomRoundTripper(t *testing.T) {
+	var tenant string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Tenant")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.HttpClient = &http.Client{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r.Header.Set("X-Tenant", "tenant-1")
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	}
+
+	var results []Dataset
+	err := m.Search.GetDatasets(&results)
+
+	if err != nil || tenant != "tenant-1" {
+		t.Errorf("expecting the request to go through the custom round tripper, got: %s, %+v", tenant, err)
+	}
+}
+
+func BenchmarkClient_Parallel(b *testing.B) {
+	cases := []struct {
+		Name      string
+		Configure func(m *Mnubo)
+	}{
+		{
+			Name:      "pooled",
+			Configure: func(m *Mnubo) {},
+		},
+		{
+			Name: "default-transport",
+			Configure: func(m *Mnubo) {
+				m.HttpClient = &http.Client{}
+			},
+		},
+		{
+			Name: "no-keep-alive",
+			Configure: func(m *Mnubo) {
+				m.CustomTransport = &http.Transport{DisableKeepAlives: true}
+			},
+		},
+	}
+
+	for _, c := range cases {
+		b.Run(c.Name, func(b *testing.B) {
+			var connections int32
+			ts := newDatasetsServer(&connections)
+			defer ts.Close()
+			m := NewClientWithToken("TOKEN", ts.URL)
+			c.Configure(m)
+
+			b.SetParallelism(8)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var results []Dataset
+				for pb.Next() {
+					if err := m.Search.GetDatasets(&results); err != nil {
+						b.Errorf("client call failed: %+v", err)
+					}
+				}
+			})
+			b.ReportMetric(float64(connections), "conns")
+		})
+	}
+}
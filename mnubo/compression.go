@@ -0,0 +1,186 @@
+package mnubo
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// Encodings of the payloads, see CompressionConfig.
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+	EncodingZstd    = "zstd"
+)
+
+// compressionLevels are the valid levels of each encoding.
+var compressionLevels = map[string][2]int{
+	EncodingGzip:    {gzip.HuffmanOnly, gzip.BestCompression},
+	EncodingDeflate: {flate.HuffmanOnly, flate.BestCompression},
+	EncodingZstd:    {1, 22},
+}
+
+// encoding returns the encoding of the requests, gzip by default.
+func (c *CompressionConfig) encoding() string {
+	if c.Encoding == "" {
+		return EncodingGzip
+	}
+	return strings.ToLower(c.Encoding)
+}
+
+// validate returns an error if the encoding or level are not supported.
+func (c *CompressionConfig) validate() error {
+	levels, ok := compressionLevels[c.encoding()]
+	if !ok {
+		return invalidConfig("compression encoding %s is not supported, use gzip, deflate or zstd", c.Encoding)
+	}
+	if c.Level != 0 && (c.Level < levels[0] || c.Level > levels[1]) {
+		return invalidConfig("compression level of %s must be between %d and %d", c.encoding(), levels[0], levels[1])
+	}
+	if c.MinSize < 0 {
+		return invalidConfig("compression minimum size must not be negative")
+	}
+	return nil
+}
+
+// requestEncoding returns the encoding of a payload, or "" if it must be sent uncompressed.
+// zstd is only used once the platform advertised it, gzip is used until then.
+func (c *CompressionConfig) requestEncoding(payload []byte, advertised *advertisedEncodings) string {
+	if !c.Request || len(payload) == 0 || len(payload) < c.MinSize {
+		return ""
+	}
+	if e := c.encoding(); e != EncodingZstd || advertised.supports(EncodingZstd) {
+		return e
+	}
+	return EncodingGzip
+}
+
+// acceptEncoding returns the Accept-Encoding header of the requests, with gzip as a fallback.
+// The configuration is checked first, as clients not created with New are not validated.
+func (c *CompressionConfig) acceptEncoding() (string, error) {
+	if err := c.validate(); err != nil {
+		return "", err
+	}
+	if e := c.encoding(); e != EncodingGzip {
+		return e + ", " + EncodingGzip, nil
+	}
+	return EncodingGzip, nil
+}
+
+// compress encodes payload with encoding at level, 0 being the default level of the encoding.
+func compress(encoding string, level int, payload []byte) ([]byte, error) {
+	var w bytes.Buffer
+	switch encoding {
+	case EncodingGzip:
+		if level == 0 {
+			level = gzip.BestSpeed
+		}
+		gw, err := gzip.NewWriterLevel(&w, level)
+		if err != nil {
+			return nil, err
+		}
+		return closeWriter(&w, gw, payload)
+	case EncodingDeflate:
+		if level == 0 {
+			level = flate.BestSpeed
+		}
+		zw, err := zlib.NewWriterLevel(&w, level)
+		if err != nil {
+			return nil, err
+		}
+		return closeWriter(&w, zw, payload)
+	case EncodingZstd:
+		return zstdEncoder(level).EncodeAll(payload, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported encoding: %s", encoding)
+	}
+}
+
+func closeWriter(buf *bytes.Buffer, w io.WriteCloser, payload []byte) ([]byte, error) {
+	if _, err := w.Write(payload); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// zstdEncoders are the encoders by level, which are safe for concurrent use with EncodeAll.
+var zstdEncoders sync.Map
+
+func zstdEncoder(level int) *zstd.Encoder {
+	if e, ok := zstdEncoders.Load(level); ok {
+		return e.(*zstd.Encoder)
+	}
+	l := zstd.SpeedDefault
+	if level != 0 {
+		l = zstd.EncoderLevelFromZstd(level)
+	}
+	e, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(l), zstd.WithEncoderConcurrency(1))
+	actual, _ := zstdEncoders.LoadOrStore(level, e)
+	return actual.(*zstd.Encoder)
+}
+
+// decompressor returns a reader uncompressing r according to a Content-Encoding header.
+func decompressor(encoding string, r io.Reader) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return ioutil.NopCloser(r), nil
+	case EncodingGzip, "x-gzip":
+		return gzip.NewReader(r)
+	case EncodingDeflate:
+		return zlib.NewReader(r)
+	case EncodingZstd:
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding: %s", encoding)
+	}
+}
+
+// decompress uncompresses a body according to a Content-Encoding header.
+func decompress(encoding string, body []byte) ([]byte, error) {
+	r, err := decompressor(encoding, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// advertisedEncodings are the request encodings SmartObjects advertised in Accept-Encoding response headers.
+type advertisedEncodings struct {
+	zstd atomic.Bool
+}
+
+// observe records the encodings advertised by a response.
+func (a *advertisedEncodings) observe(res *http.Response) {
+	if a == nil {
+		return
+	}
+	for _, v := range res.Header.Values("Accept-Encoding") {
+		for _, e := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(strings.SplitN(e, ";", 2)[0]), EncodingZstd) {
+				a.zstd.Store(true)
+			}
+		}
+	}
+}
+
+func (a *advertisedEncodings) supports(encoding string) bool {
+	return a != nil && encoding == EncodingZstd && a.zstd.Load()
+}
@@ -0,0 +1,155 @@
+package mnubo
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	payload := []byte(strings.Repeat(`{"x_object":{"x_device_id":"device"},"x_event_type":"type"},`, 100))
+	cases := []struct {
+		Encoding string
+		Level    int
+	}{
+		{EncodingGzip, 0},
+		{EncodingGzip, 9},
+		{EncodingDeflate, 0},
+		{EncodingDeflate, 1},
+		{EncodingZstd, 0},
+		{EncodingZstd, 19},
+	}
+	for i, c := range cases {
+		compressed, err := compress(c.Encoding, c.Level, payload)
+		if err != nil || len(compressed) >= len(payload) {
+			t.Errorf("%d, expecting compressed payload, got: %d bytes (%+v)", i, len(compressed), err)
+			continue
+		}
+		decompressed, err := decompress(c.Encoding, compressed)
+		if err != nil || string(decompressed) != string(payload) {
+			t.Errorf("%d, expecting payload to be restored, got: %+v", i, err)
+		}
+	}
+
+	if _, err := decompress("br", payload); err == nil {
+		t.Errorf("expecting unsupported encoding error")
+	}
+}
+
+func TestCompressionConfig_Validate(t *testing.T) {
+	cases := []struct {
+		Config CompressionConfig
+		Error  string
+	}{
+		{CompressionConfig{}, ""},
+		{CompressionConfig{Encoding: "ZSTD", Level: 22}, ""},
+		{CompressionConfig{Encoding: EncodingDeflate, Level: 9, MinSize: 1024}, ""},
+		{CompressionConfig{Encoding: "br"}, "compression encoding br is not supported"},
+		{CompressionConfig{Level: 10}, "compression level of gzip must be between -2 and 9"},
+		{CompressionConfig{Encoding: EncodingZstd, Level: 23}, "compression level of zstd must be between 1 and 22"},
+		{CompressionConfig{MinSize: -1}, "compression minimum size must not be negative"},
+	}
+	for i, c := range cases {
+		err := c.Config.validate()
+
+		if c.Error == "" {
+			if err != nil {
+				t.Errorf("%d, expecting no error, got: %+v", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), c.Error) {
+			t.Errorf("%d, expecting error: %s, got: %+v", i, c.Error, err)
+		}
+	}
+}
+
+func TestCompression_Client(t *testing.T) {
+	var mu sync.Mutex
+	var encodings []string
+	advertise := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		if _, err := decompress(r.Header.Get("Content-Encoding"), body); err != nil {
+			t.Errorf("invalid %s body: %+v", r.Header.Get("Content-Encoding"), err)
+		}
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		if advertise {
+			w.Header().Set("Accept-Encoding", "gzip, zstd")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/oauth/token" {
+			w.Write([]byte(`{"access_token":"TOKEN","expires_in":3600000}`))
+			return
+		}
+		// Responses are encoded with the first encoding accepted by the client.
+		encoding := strings.Split(r.Header.Get("Accept-Encoding"), ",")[0]
+		response, _ := compress(encoding, 0, []byte(`[{"key":"event"}]`))
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(response)
+	}))
+	defer ts.Close()
+
+	events := []map[string]string{{"x_event_type": strings.Repeat("type", 100)}}
+	cases := []struct {
+		Compression CompressionConfig
+		Advertise   bool
+		Expected    []string
+	}{
+		{CompressionConfig{Request: true, Response: true}, false, []string{"", "gzip", ""}},
+		{CompressionConfig{Request: true, Response: true, MinSize: 1 << 20}, false, []string{"", "", ""}},
+		{CompressionConfig{Request: true, Response: true, Encoding: EncodingDeflate, Level: 9}, false, []string{"", "deflate", ""}},
+		{CompressionConfig{Request: true, Response: true, Encoding: EncodingZstd}, false, []string{"", "gzip", ""}},
+		{CompressionConfig{Request: true, Response: true, Encoding: EncodingZstd}, true, []string{"", "zstd", ""}},
+	}
+	for i, c := range cases {
+		mu.Lock()
+		encodings = nil
+		advertise = c.Advertise
+		mu.Unlock()
+
+		m := NewClient("CLIENT_ID", "CLIENT_SECRET", ts.URL)
+		m.Compression = c.Compression
+		var results []interface{}
+		err := m.Events.Send(events, SendEventsOptions{}, &results)
+		var datasets []Dataset
+		if err == nil {
+			err = m.Search.GetDatasets(&datasets)
+		}
+
+		if err != nil || len(datasets) != 1 || datasets[0].Key != "event" {
+			t.Errorf("%d, expecting decoded response, got: %+v (%+v)", i, datasets, err)
+		}
+		// The token request is never compressed, nor requests without payload.
+		if strings.Join(encodings, ",") != strings.Join(c.Expected, ",") {
+			t.Errorf("%d, expecting encodings: %+v, got: %+v", i, c.Expected, encodings)
+		}
+	}
+}
+
+func TestCompression_InvalidAcceptEncoding(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.Compression = CompressionConfig{Response: true, Encoding: "br"}
+	var datasets []Dataset
+	err := m.Search.GetDatasets(&datasets)
+
+	if !errors.Is(err, ErrInvalidConfig) || requests != 0 {
+		t.Errorf("expecting ErrInvalidConfig without sending the request, got %d requests: %+v", requests, err)
+	}
+}
@@ -0,0 +1,262 @@
+package mnubo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Environment variables read by NewFromEnv, in addition to the ones of NewEnvTokenSource.
+	EnvHost               = "MNUBO_HOST"
+	EnvEnvironment        = "MNUBO_ENVIRONMENT"
+	EnvScope              = "MNUBO_SCOPE"
+	EnvTokenCacheDir      = "MNUBO_TOKEN_CACHE_DIR"
+	EnvCompressRequests   = "MNUBO_COMPRESS_REQUESTS"
+	EnvCompressResponses  = "MNUBO_COMPRESS_RESPONSES"
+	EnvTimeout            = "MNUBO_TIMEOUT"
+	EnvMaxAttempts        = "MNUBO_MAX_ATTEMPTS"
+	EnvMaxElapsedTime     = "MNUBO_MAX_ELAPSED_TIME"
+	EnvRetryNetworkErrors = "MNUBO_RETRY_NETWORK_ERRORS"
+)
+
+// Duration is a time.Duration read from strings like "10s" or "1m30s" in configuration files.
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Config is the configuration of a client, read from a file by NewFromConfigFile or from the environment by NewFromEnv.
+// Settings which are not set keep their default value.
+type Config struct {
+	// Environment is sandbox, production or a host, see LookupEnvironment. Host has precedence over it.
+	Environment  string `json:"environment" yaml:"environment"`
+	Host         string `json:"host" yaml:"host"`
+	ClientId     string `json:"client_id" yaml:"client_id"`
+	ClientSecret string `json:"client_secret" yaml:"client_secret"`
+	ClientToken  string `json:"client_token" yaml:"client_token"`
+	Scope        string `json:"scope" yaml:"scope"`
+	// Scopes of the Ingestion, Search and Modeler endpoints, Scope is used for the other ones.
+	Scopes struct {
+		Ingestion string `json:"ingestion" yaml:"ingestion"`
+		Search    string `json:"search" yaml:"search"`
+		Modeler   string `json:"modeler" yaml:"modeler"`
+	} `json:"scopes" yaml:"scopes"`
+	// TokenCacheDir persists the tokens obtained with client credentials in a directory, see FileTokenCache.
+	TokenCacheDir     string `json:"token_cache_dir" yaml:"token_cache_dir"`
+	CompressRequests  bool   `json:"compress_requests" yaml:"compress_requests"`
+	CompressResponses bool   `json:"compress_responses" yaml:"compress_responses"`
+	// Compression of the requests and responses when enabled: gzip (default), deflate or zstd, see CompressionConfig.
+	Compression struct {
+		Encoding string `json:"encoding" yaml:"encoding"`
+		Level    int    `json:"level" yaml:"level"`
+		MinSize  int    `json:"min_size" yaml:"min_size"`
+	} `json:"compression" yaml:"compression"`
+	// Timeout of each HTTP request.
+	Timeout *Duration `json:"timeout" yaml:"timeout"`
+	Retry   struct {
+		MaxAttempts          *int      `json:"max_attempts" yaml:"max_attempts"`
+		MaxElapsedTime       *Duration `json:"max_elapsed_time" yaml:"max_elapsed_time"`
+		RetryNetworkErrors   *bool     `json:"retry_network_errors" yaml:"retry_network_errors"`
+		RetryableStatusCodes []int     `json:"retryable_status_codes" yaml:"retryable_status_codes"`
+	} `json:"retry" yaml:"retry"`
+}
+
+// Options returns the options applying c.
+func (c *Config) Options() []Option {
+	opts := []Option{
+		WithCompression(CompressionConfig{
+			Request:  c.CompressRequests,
+			Response: c.CompressResponses,
+			Encoding: c.Compression.Encoding,
+			Level:    c.Compression.Level,
+			MinSize:  c.Compression.MinSize,
+		}),
+	}
+
+	if c.Environment != "" && c.Host == "" {
+		opts = append(opts, func(m *Mnubo) error {
+			env, err := LookupEnvironment(c.Environment)
+			if err != nil {
+				return err
+			}
+			return WithEnvironment(env)(m)
+		})
+	}
+	if c.Host != "" {
+		opts = append(opts, WithHost(c.Host))
+	}
+
+	if c.ClientToken != "" {
+		opts = append(opts, WithToken(c.ClientToken))
+	}
+	if c.ClientId != "" || c.ClientSecret != "" {
+		opts = append(opts, WithClientCredentials(c.ClientId, c.ClientSecret))
+	}
+	if c.Scope != "" {
+		opts = append(opts, WithScope(c.Scope))
+	}
+	if c.Scopes.Ingestion != "" || c.Scopes.Search != "" || c.Scopes.Modeler != "" {
+		opts = append(opts, func(m *Mnubo) error {
+			m.Scopes.Ingestion = c.Scopes.Ingestion
+			m.Scopes.Search = c.Scopes.Search
+			m.Scopes.Modeler = c.Scopes.Modeler
+			return nil
+		})
+	}
+	if c.TokenCacheDir != "" {
+		opts = append(opts, WithTokenCache(NewFileTokenCache(c.TokenCacheDir)))
+	}
+	if c.Timeout != nil {
+		opts = append(opts, WithTimeout(time.Duration(*c.Timeout)))
+	}
+
+	opts = append(opts, func(m *Mnubo) error {
+		if c.Retry.MaxAttempts != nil {
+			m.RetryPolicy.MaxAttempts = *c.Retry.MaxAttempts
+		}
+		if c.Retry.MaxElapsedTime != nil {
+			m.ExponentialBackoff.MaxElapsedTime = time.Duration(*c.Retry.MaxElapsedTime)
+		}
+		if c.Retry.RetryNetworkErrors != nil {
+			m.RetryPolicy.RetryNetworkErrors = *c.Retry.RetryNetworkErrors
+		}
+		if c.Retry.RetryableStatusCodes != nil {
+			for _, sc := range c.Retry.RetryableStatusCodes {
+				if sc < 100 || sc > 599 {
+					return invalidConfig("retryable status code %d is not a valid HTTP status code", sc)
+				}
+			}
+			m.RetryPolicy.RetryableStatusCodes = c.Retry.RetryableStatusCodes
+		}
+		return nil
+	})
+	return opts
+}
+
+// NewFromConfig creates a new Mnubo structure from c, then applies opts.
+func NewFromConfig(c Config, opts ...Option) (*Mnubo, error) {
+	return New(append(c.Options(), opts...)...)
+}
+
+// NewFromConfigFile creates a new Mnubo structure from a YAML (.yaml, .yml) or JSON file, then applies opts.
+// Unknown settings are rejected. See Config for the settings.
+func NewFromConfigFile(path string, opts ...Option) (*Mnubo, error) {
+	c, err := LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromConfig(c, opts...)
+}
+
+// LoadConfigFile reads a client configuration from a YAML (.yaml, .yml) or JSON file.
+func LoadConfigFile(path string) (Config, error) {
+	c := Config{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		d := yaml.NewDecoder(bytes.NewReader(data))
+		d.KnownFields(true)
+		err = d.Decode(&c)
+	case ".json":
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.DisallowUnknownFields()
+		err = d.Decode(&c)
+	default:
+		return c, invalidConfig("config file %s must be .yaml, .yml or .json", path)
+	}
+
+	if err != nil {
+		return c, invalidConfig("unable to parse config file %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// NewFromEnv creates a new Mnubo structure from environment variables, then applies opts.
+// The host is read from MNUBO_HOST, or MNUBO_ENVIRONMENT (sandbox or production),
+// and the credentials from MNUBO_CLIENT_ID and MNUBO_CLIENT_SECRET, or MNUBO_CLIENT_TOKEN, but not both.
+// The optional settings are read from MNUBO_SCOPE, MNUBO_TOKEN_CACHE_DIR, MNUBO_COMPRESS_REQUESTS,
+// MNUBO_COMPRESS_RESPONSES, MNUBO_TIMEOUT, MNUBO_MAX_ATTEMPTS, MNUBO_MAX_ELAPSED_TIME and MNUBO_RETRY_NETWORK_ERRORS.
+func NewFromEnv(opts ...Option) (*Mnubo, error) {
+	c, err := LoadConfigEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewFromConfig(c, opts...)
+}
+
+// LoadConfigEnv reads a client configuration from environment variables, see NewFromEnv.
+func LoadConfigEnv() (Config, error) {
+	c := Config{
+		Environment:   os.Getenv(EnvEnvironment),
+		Host:          os.Getenv(EnvHost),
+		ClientId:      os.Getenv(EnvClientId),
+		ClientSecret:  os.Getenv(EnvClientSecret),
+		ClientToken:   os.Getenv(EnvClientToken),
+		Scope:         os.Getenv(EnvScope),
+		TokenCacheDir: os.Getenv(EnvTokenCacheDir),
+	}
+
+	var errs []error
+	parse := func(name string, parse func(string) error) {
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			if err := parse(v); err != nil {
+				errs = append(errs, invalidConfig("%s: %v", name, err))
+			}
+		}
+	}
+	duration := func(d **Duration) func(string) error {
+		return func(v string) error {
+			*d = new(Duration)
+			return (*d).UnmarshalText([]byte(v))
+		}
+	}
+
+	parse(EnvCompressRequests, func(v string) (err error) {
+		c.CompressRequests, err = strconv.ParseBool(v)
+		return err
+	})
+	parse(EnvCompressResponses, func(v string) (err error) {
+		c.CompressResponses, err = strconv.ParseBool(v)
+		return err
+	})
+	parse(EnvTimeout, duration(&c.Timeout))
+	parse(EnvMaxElapsedTime, duration(&c.Retry.MaxElapsedTime))
+	parse(EnvMaxAttempts, func(v string) error {
+		n, err := strconv.Atoi(v)
+		c.Retry.MaxAttempts = &n
+		return err
+	})
+	parse(EnvRetryNetworkErrors, func(v string) error {
+		b, err := strconv.ParseBool(v)
+		c.Retry.RetryNetworkErrors = &b
+		return err
+	})
+
+	return c, errors.Join(errs...)
+}
@@ -0,0 +1,131 @@
+package mnubo
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("unable to write config file: %+v", err)
+	}
+	return path
+}
+
+func TestNewFromConfigFile(t *testing.T) {
+	yamlConfig := `
+host: https://rest.sandbox.mnubo.com
+client_id: CLIENT_ID
+client_secret: CLIENT_SECRET
+scope: READ
+scopes:
+  ingestion: WRITE
+compress_requests: true
+timeout: 30s
+retry:
+  max_attempts: 3
+  max_elapsed_time: 1m
+  retry_network_errors: false
+  retryable_status_codes: [503]
+`
+	jsonConfig := `{
+	"host": "https://rest.sandbox.mnubo.com",
+	"client_id": "CLIENT_ID",
+	"client_secret": "CLIENT_SECRET",
+	"scope": "READ",
+	"scopes": {"ingestion": "WRITE"},
+	"compress_requests": true,
+	"timeout": "30s",
+	"retry": {"max_attempts": 3, "max_elapsed_time": "1m", "retry_network_errors": false, "retryable_status_codes": [503]}
+}`
+	cases := []struct {
+		Name    string
+		Content string
+	}{
+		{"config.yaml", yamlConfig},
+		{"config.yml", yamlConfig},
+		{"config.json", jsonConfig},
+	}
+	for i, c := range cases {
+		m, err := NewFromConfigFile(writeConfigFile(t, c.Name, c.Content))
+
+		if err != nil {
+			t.Errorf("%d, unable to create client: %+v", i, err)
+			continue
+		}
+		if m.Host != "https://rest.sandbox.mnubo.com" || m.ClientId != "CLIENT_ID" || m.ClientSecret != "CLIENT_SECRET" || m.Scopes.Default != "READ" || m.Scopes.Ingestion != "WRITE" {
+			t.Errorf("%d, expecting host and credentials, got: %+v", i, m)
+		}
+		if !m.Compression.Request || m.Compression.Response || m.Timeout != 30*time.Second {
+			t.Errorf("%d, expecting compression and timeout, got: %+v", i, m)
+		}
+		p := m.RetryPolicy
+		if p.MaxAttempts != 3 || p.RetryNetworkErrors || len(p.RetryableStatusCodes) != 1 || p.RetryableStatusCodes[0] != http.StatusServiceUnavailable || m.ExponentialBackoff.MaxElapsedTime != time.Minute {
+			t.Errorf("%d, expecting retry settings, got: %+v", i, p)
+		}
+		if p.Jitter != DefaultRetryJitter {
+			t.Errorf("%d, expecting unset settings to keep their default, got: %+v", i, p)
+		}
+	}
+}
+
+func TestNewFromConfigFile_Errors(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Content  string
+		Expected string
+	}{
+		{"config.toml", `host = "https://rest.sandbox.mnubo.com"`, "must be .yaml, .yml or .json"},
+		{"config.yaml", "host: https://rest.sandbox.mnubo.com\nclient_token: TOKEN\nhots: typo", "field hots not found"},
+		{"config.json", `{"host": "https://rest.sandbox.mnubo.com", "client_token": "TOKEN", "timeout": 10}`, "unable to parse config file"},
+		{"config.yaml", "host: https://rest.sandbox.mnubo.com\nclient_token: TOKEN\ntimeout: soon", "invalid duration"},
+		{"config.yaml", "client_token: TOKEN", "host is required"},
+		{"config.json", `{"host": "https://rest.sandbox.mnubo.com", "client_id": "CLIENT_ID"}`, "client secret is required"},
+		{"config.json", `{"host": "https://rest.sandbox.mnubo.com", "client_token": "TOKEN", "retry": {"retryable_status_codes": [5030]}}`, "5030 is not a valid HTTP status code"},
+	}
+	for i, c := range cases {
+		_, err := NewFromConfigFile(writeConfigFile(t, c.Name, c.Content))
+
+		if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), c.Expected) {
+			t.Errorf("%d, expecting: %s, got: %+v", i, c.Expected, err)
+		}
+	}
+
+	if _, err := NewFromConfigFile(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expecting missing file error, got: %+v", err)
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv(EnvHost, "https://rest.sandbox.mnubo.com")
+	t.Setenv(EnvClientId, "")
+	t.Setenv(EnvClientSecret, "")
+	t.Setenv(EnvClientToken, "TOKEN")
+	t.Setenv(EnvCompressResponses, "true")
+	t.Setenv(EnvTimeout, "5s")
+	t.Setenv(EnvMaxAttempts, "4")
+
+	m, err := NewFromEnv()
+
+	if err != nil {
+		t.Fatalf("unable to create client: %+v", err)
+	}
+	if m.ClientToken != "TOKEN" || !m.Compression.Response || m.Compression.Request || m.Timeout != 5*time.Second || m.RetryPolicy.MaxAttempts != 4 {
+		t.Errorf("expecting settings from the environment, got: %+v", m)
+	}
+
+	t.Setenv(EnvTimeout, "5")
+	t.Setenv(EnvRetryNetworkErrors, "maybe")
+	_, err = NewFromEnv()
+
+	if !errors.Is(err, ErrInvalidConfig) || !strings.Contains(err.Error(), EnvTimeout) || !strings.Contains(err.Error(), EnvRetryNetworkErrors) {
+		t.Errorf("expecting errors for %s and %s, got: %+v", EnvTimeout, EnvRetryNetworkErrors, err)
+	}
+}
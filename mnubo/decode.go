@@ -0,0 +1,163 @@
+package mnubo
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// maxResponseDrain is how much of the rest of a decoded response body is read, so its connection can be reused.
+const maxResponseDrain = 4096
+
+// responseStream is implemented by results decoded while the response is received, like SearchRows.
+// Their attempts are not retried once the response is received, as part of it may have been handled.
+type responseStream interface {
+	decodeStream(d *json.Decoder) error
+}
+
+// responseReadError is an error reading a response body, as opposed to an invalid body.
+type responseReadError struct {
+	err error
+}
+
+func (e *responseReadError) Error() string {
+	return "unable to read response: " + e.err.Error()
+}
+
+func (e *responseReadError) Unwrap() error {
+	return e.err
+}
+
+// errorReader remembers the last error of r other than io.EOF.
+type errorReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
+
+// RawResponse gives the status code, headers and uncompressed body of a response, whatever its content type.
+// It can be given as the results of any call, and is also filled when the call fails with an APIError.
+type RawResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Text returns the body of the response as a string.
+func (r *RawResponse) Text() string {
+	return string(r.Body)
+}
+
+// setRawResponse fills response with res and its uncompressed body if it is a *RawResponse.
+func setRawResponse(response interface{}, res *http.Response, body []byte) {
+	if raw, ok := response.(*RawResponse); ok {
+		*raw = RawResponse{
+			StatusCode: res.StatusCode,
+			Header:     res.Header,
+			Body:       body,
+		}
+	}
+}
+
+// isRawResponse returns true if response receives the body of a response as is: *RawResponse, *string or *[]byte.
+func isRawResponse(response interface{}) bool {
+	switch response.(type) {
+	case *RawResponse, *string, *[]byte:
+		return true
+	}
+	return false
+}
+
+// decodeResponse decodes the JSON body of a successful response into response while it is received,
+// uncompressing it on the fly according to its Content-Encoding. The body is never held in memory as a whole.
+// The bodies of any content type are read into *RawResponse, *string and *[]byte results,
+// and text/plain bodies into *interface{} results as a string. Other content types are discarded.
+// Errors reading or uncompressing the body are returned as a *responseReadError.
+func decodeResponse(res *http.Response, response interface{}) error {
+	body := &errorReader{r: res.Body}
+	wrap := func(err error) error {
+		if body.err != nil {
+			return &responseReadError{err: body.err}
+		}
+		return err
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	_, isInterface := response.(*interface{})
+	text := isInterface && strings.Contains(contentType, "text/plain")
+	if !isRawResponse(response) && !text && !strings.Contains(contentType, "application/json") {
+		_, err := io.Copy(ioutil.Discard, body)
+		return wrap(err)
+	}
+
+	r, err := decompressor(res.Header.Get("Content-Encoding"), body)
+	if err != nil {
+		return wrap(err)
+	}
+	defer r.Close()
+	decoded := &errorReader{r: r}
+
+	if isRawResponse(response) || text {
+		data, err := ioutil.ReadAll(decoded)
+		if err != nil {
+			return wrap(&responseReadError{err: err})
+		}
+		switch v := response.(type) {
+		case *string:
+			*v = string(data)
+		case *[]byte:
+			*v = data
+		case *interface{}:
+			*v = string(data)
+		default:
+			setRawResponse(response, res, data)
+		}
+		return nil
+	}
+
+	d := json.NewDecoder(decoded)
+	if s, ok := response.(responseStream); ok {
+		err = s.decodeStream(d)
+	} else {
+		err = d.Decode(response)
+	}
+	if err != nil {
+		if decoded.err != nil && body.err == nil {
+			// The body was received but can't be uncompressed, like a truncated gzip stream.
+			return &responseReadError{err: decoded.err}
+		}
+		return wrap(err)
+	}
+
+	io.CopyN(ioutil.Discard, res.Body, maxResponseDrain)
+	return nil
+}
+
+// readError returns err if it is a *responseReadError, nil otherwise.
+func readError(err error) error {
+	if _, ok := err.(*responseReadError); ok {
+		return err
+	}
+	return nil
+}
+
+// isRetryableReadError returns true if reading the response of an attempt failed and it can be sent again.
+func isRetryableReadError(err error, response interface{}, b *retryBackOff) bool {
+	re, ok := err.(*responseReadError)
+	if !ok {
+		return false
+	}
+	if _, ok := response.(responseStream); ok {
+		return false
+	}
+	return b.isRetryableError(re.err)
+}
@@ -0,0 +1,284 @@
+package mnubo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func gzipBytes(data string) []byte {
+	compressed, _ := compress(EncodingGzip, 0, []byte(data))
+	return compressed
+}
+
+func TestDecodeResponse(t *testing.T) {
+	compressed := gzipBytes(`{"isValid":true}`)
+	cases := []struct {
+		ContentType     string
+		ContentEncoding string
+		Body            []byte
+		Expected        QueryValidation
+		ReadError       bool
+		Error           bool
+	}{
+		{"application/json", "", []byte(`{"isValid":true}`), QueryValidation{IsValid: true}, false, false},
+		{"application/json; charset=utf-8", "gzip", compressed, QueryValidation{IsValid: true}, false, false},
+		{"text/plain", "", []byte(`{"isValid":true}`), QueryValidation{}, false, false},
+		{"application/json", "gzip", compressed[:len(compressed)/2], QueryValidation{IsValid: true}, true, true},
+		{"application/json", "gzip", []byte(`{"isValid":true}`), QueryValidation{}, false, true},
+		{"application/json", "", []byte(`{"isValid":`), QueryValidation{}, false, true},
+	}
+	for i, c := range cases {
+		res := &http.Response{
+			Header: http.Header{"Content-Type": {c.ContentType}, "Content-Encoding": {c.ContentEncoding}},
+			Body:   ioutil.NopCloser(bytes.NewReader(c.Body)),
+		}
+		results := QueryValidation{}
+		err := decodeResponse(res, &results)
+
+		if (err != nil) != c.Error || (readError(err) != nil) != c.ReadError {
+			t.Errorf("%d, expecting error: %t, read error: %t, got: %+v", i, c.Error, c.ReadError, err)
+		}
+		if !c.Error && results.IsValid != c.Expected.IsValid {
+			t.Errorf("%d, expecting: %+v, got: %+v", i, c.Expected, results)
+		}
+	}
+}
+
+func TestDecodeResponse_RetryTruncated(t *testing.T) {
+	body := gzipBytes(`{"columns":[{"label":"count","type":"long"}],"rows":[[1],[2]]}`)
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Write(body[:len(body)/2])
+			return
+		}
+		w.Write(body)
+	}))
+	defer ts.Close()
+
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	results := SearchResults{}
+	if err := m.Search.CreateBasicQuery(map[string]string{}, &results); err != nil || len(results.Rows) != 2 {
+		t.Errorf("expecting the truncated response to be retried, got: %+v (%+v)", results, err)
+	}
+
+	// Rows may have been handled, streams are not retried.
+	atomic.StoreInt32(&attempts, 0)
+	rows := SearchRows{}
+	err := m.Search.CreateBasicQuery(map[string]string{}, &rows)
+	if readError(err) == nil || !errors.Is(err, io.ErrUnexpectedEOF) || attempts != 1 {
+		t.Errorf("expecting a single attempt failing with a read error, got: %d (%+v)", attempts, err)
+	}
+}
+
+func TestSearchRows(t *testing.T) {
+	var status int32 = http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		if atomic.LoadInt32(&status) != http.StatusOK {
+			w.Write(gzipBytes(`{"errorCode":"INVALID_QUERY"}`))
+			return
+		}
+		w.Write(gzipBytes(`{"columns":[{"label":"x_device_id","type":"TEXT"},{"label":"count","type":"LONG"}],` +
+			`"rows":[["device-1",1],["device-2",2],["device-3",3]],"extra":{"ignored":[1,2]}}`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	var received [][]interface{}
+	rows := SearchRows{
+		OnRow: func(row []interface{}) error {
+			received = append(received, row)
+			return nil
+		},
+	}
+	if err := m.Search.CreateBasicQuery(map[string]string{}, &rows); err != nil {
+		t.Fatalf("query failed: %+v", err)
+	}
+	if len(rows.Columns) != 2 || rows.Columns[1].Label != "count" {
+		t.Errorf("expecting columns, got: %+v", rows.Columns)
+	}
+	if len(received) != 3 || received[2][0] != "device-3" || received[2][1] != 3.0 {
+		t.Errorf("expecting 3 rows, got: %+v", received)
+	}
+
+	failure := errors.New("failure")
+	rows.OnRow = func(row []interface{}) error {
+		return failure
+	}
+	if err := m.Search.CreateBasicQuery(map[string]string{}, &rows); !errors.Is(err, failure) {
+		t.Errorf("expecting the error of OnRow, got: %+v", err)
+	}
+
+	var devices []string
+	for row, err := range m.Search.Rows(context.Background(), map[string]string{}) {
+		if err != nil {
+			t.Fatalf("iteration failed: %+v", err)
+		}
+		devices = append(devices, row[0].(string))
+		if len(devices) == 2 {
+			break
+		}
+	}
+	if strings.Join(devices, ",") != "device-1,device-2" {
+		t.Errorf("expecting iteration to stop after 2 rows, got: %+v", devices)
+	}
+
+	atomic.StoreInt32(&status, http.StatusBadRequest)
+	for _, err := range m.Search.Rows(context.Background(), map[string]string{}) {
+		var apiError *APIError
+		if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusBadRequest {
+			t.Errorf("expecting the query error, got: %+v", err)
+		}
+	}
+}
+
+// benchmarkResponseSize is the size of the uncompressed search results used by the benchmarks.
+const benchmarkResponseSize = 256 << 20
+
+// newSearchResultsServer streams search results of about size bytes, without holding them in memory.
+func newSearchResultsServer(size int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		bw := bufio.NewWriter(w)
+		written, _ := bw.WriteString(`{"columns":[{"label":"x_device_id","type":"TEXT"},{"label":"temperature","type":"DOUBLE"},{"label":"x_timestamp","type":"DATETIME"}],"rows":[`)
+		for i := 0; written < size; i++ {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			n, _ := fmt.Fprintf(bw, `["device-%08d",%d.5,"2020-01-01T00:00:00.000Z"]`, i, i%100)
+			written += n + 1
+		}
+		bw.WriteString(`]}`)
+		bw.Flush()
+	}))
+}
+
+// measurePeakHeap samples the heap while f runs and returns its peak, in MB.
+func measurePeakHeap(f func()) float64 {
+	runtime.GC()
+	var peak uint64
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		var stats runtime.MemStats
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > peak {
+				peak = stats.HeapAlloc
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond * 10):
+			}
+		}
+	}()
+	f()
+	close(done)
+	wg.Wait()
+	return float64(peak) / (1 << 20)
+}
+
+func benchmarkSearch(b *testing.B, results func() interface{}) {
+	ts := newSearchResultsServer(benchmarkResponseSize)
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.Timeout = time.Minute * 5
+	b.SetBytes(benchmarkResponseSize)
+	b.ReportAllocs()
+
+	var peak float64
+	for i := 0; i < b.N; i++ {
+		heap := measurePeakHeap(func() {
+			if err := m.Search.CreateBasicQuery(map[string]string{}, results()); err != nil {
+				b.Fatalf("query failed: %+v", err)
+			}
+		})
+		if heap > peak {
+			peak = heap
+		}
+	}
+	b.ReportMetric(peak, "peak-heap-MB")
+}
+
+// BenchmarkSearchRows streams the rows, its peak heap does not depend on the size of the results.
+func BenchmarkSearchRows(b *testing.B) {
+	benchmarkSearch(b, func() interface{} {
+		count := 0
+		return &SearchRows{
+			OnRow: func(row []interface{}) error {
+				count++
+				return nil
+			},
+		}
+	})
+}
+
+// BenchmarkSearchResults decodes every row in memory, for comparison.
+func BenchmarkSearchResults(b *testing.B) {
+	benchmarkSearch(b, func() interface{} {
+		return &SearchResults{}
+	})
+}
+
+func TestRawResponse(t *testing.T) {
+	var status int32 = http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("X-Request-Id", "request-1")
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+		w.Write(gzipBytes("Query accepted"))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	var text string
+	if err := m.Search.CreateBasicQuery(map[string]string{}, &text); err != nil || text != "Query accepted" {
+		t.Errorf("expecting the text of the response, got: %q (%+v)", text, err)
+	}
+	var data []byte
+	if err := m.Search.CreateBasicQuery(map[string]string{}, &data); err != nil || string(data) != "Query accepted" {
+		t.Errorf("expecting the bytes of the response, got: %q (%+v)", data, err)
+	}
+	var result interface{}
+	if err := m.Search.CreateBasicQuery(map[string]string{}, &result); err != nil || result != "Query accepted" {
+		t.Errorf("expecting the text of the response, got: %+v (%+v)", result, err)
+	}
+
+	raw := RawResponse{}
+	if err := m.Search.CreateBasicQuery(map[string]string{}, &raw); err != nil {
+		t.Fatalf("query failed: %+v", err)
+	}
+	if raw.StatusCode != http.StatusOK || raw.Text() != "Query accepted" || raw.Header.Get("X-Request-Id") != "request-1" {
+		t.Errorf("unexpected raw response: %+v", raw)
+	}
+
+	atomic.StoreInt32(&status, http.StatusBadRequest)
+	raw = RawResponse{}
+	err := m.Search.CreateBasicQuery(map[string]string{}, &raw)
+	if !IsBadRequest(err) || raw.StatusCode != http.StatusBadRequest || raw.Text() != "Query accepted" {
+		t.Errorf("expecting the raw response of the error, got: %+v (%+v)", raw, err)
+	}
+}
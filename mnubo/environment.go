@@ -0,0 +1,127 @@
+package mnubo
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Environment is a SmartObjects platform the client can connect to.
+type Environment struct {
+	Name string
+	Host string
+}
+
+var (
+	// Sandbox is the SmartObjects environment used to develop and test data models.
+	Sandbox = Environment{Name: "sandbox", Host: "https://rest.sandbox.mnubo.com"}
+	// Production is the SmartObjects environment data models are promoted to.
+	Production = Environment{Name: "production", Host: "https://rest.api.mnubo.com"}
+)
+
+// CustomEnvironment creates an Environment for another host, which is normalized by NormalizeHost.
+func CustomEnvironment(name string, host string) (Environment, error) {
+	normalized, err := NormalizeHost(host)
+	if err != nil {
+		return Environment{}, err
+	}
+	if name == "" {
+		name = "custom"
+	}
+	return Environment{Name: name, Host: normalized}, nil
+}
+
+// LookupEnvironment returns the environment named sandbox or production, ignoring case.
+// Any other name must be a host, which gives a custom environment.
+func LookupEnvironment(name string) (Environment, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case Sandbox.Name:
+		return Sandbox, nil
+	case Production.Name:
+		return Production, nil
+	case "":
+		return Environment{}, invalidConfig("environment name is required")
+	}
+	if !strings.ContainsAny(name, ".:/") {
+		return Environment{}, invalidConfig("unknown environment %q, expecting sandbox, production or a host", name)
+	}
+	return CustomEnvironment("", name)
+}
+
+// NormalizeHost validates a host URL, adding the https scheme when it is missing and removing trailing slashes.
+// Hosts must not have a query or fragment.
+func NormalizeHost(host string) (string, error) {
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return "", invalidConfig("host is required")
+	}
+	if !strings.Contains(host, "://") {
+		host = "https://" + host
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", invalidConfig("host %q is not a valid URL: %v", host, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", invalidConfig("host %q is not an http(s) URL", host)
+	}
+	if u.Host == "" {
+		return "", invalidConfig("host %q has no host name", host)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", invalidConfig("host %q must not have credentials, a query or a fragment", host)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// WithEnvironment sends requests to the host of env.
+func WithEnvironment(env Environment) Option {
+	return func(m *Mnubo) error {
+		if env.Host == "" {
+			return invalidConfig("environment %q has no host", env.Name)
+		}
+		m.Host = env.Host
+		return nil
+	}
+}
+
+// ClientSet pairs a sandbox and a production client, to promote data models from one to the other.
+type ClientSet struct {
+	Sandbox    *Mnubo
+	Production *Mnubo
+}
+
+// NewClientSet creates a sandbox and a production client with their own credentials and the same opts.
+// For instance: NewClientSet(WithClientCredentials(sandboxId, sandboxSecret), WithClientCredentials(productionId, productionSecret)).
+func NewClientSet(sandboxCredentials Option, productionCredentials Option, opts ...Option) (*ClientSet, error) {
+	sandbox, err := New(append([]Option{WithEnvironment(Sandbox), sandboxCredentials}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox client: %w", err)
+	}
+	production, err := New(append([]Option{WithEnvironment(Production), productionCredentials}, opts...)...)
+	if err != nil {
+		return nil, fmt.Errorf("production client: %w", err)
+	}
+	return &ClientSet{
+		Sandbox:    sandbox,
+		Production: production,
+	}, nil
+}
+
+// Client returns the client of env, or nil if it is neither Sandbox nor Production.
+func (s *ClientSet) Client(env Environment) *Mnubo {
+	switch env.Name {
+	case Sandbox.Name:
+		return s.Sandbox
+	case Production.Name:
+		return s.Production
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package mnubo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// requestIdHeaders are the response headers which can correlate a request with the platform logs.
+var requestIdHeaders = []string{"X-Request-Id", "X-Correlation-Id", "X-B3-TraceId"}
+
+// APIError is returned when SmartObjects responds with a non 2xx status code.
+// Use errors.As to access it, or helpers like IsNotFound to check for common failures.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	// Body is the raw (uncompressed) response body.
+	Body []byte
+	// Message is the error message reported by the platform, if any.
+	Message string
+	// RequestId is the correlation id of the request, if the platform returned one.
+	RequestId string
+	Header    http.Header
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("The server responded with StatusCode: %d - Body: %s", e.StatusCode, e.Body)
+}
+
+// isTokenRejected returns true if the error denotes a revoked, expired or invalid access token.
+func (e *APIError) isTokenRejected() bool {
+	return e.StatusCode == http.StatusUnauthorized ||
+		strings.Contains(e.Header.Get("WWW-Authenticate"), "invalid_token")
+}
+
+// newAPIError creates an APIError from a response and its uncompressed body.
+func newAPIError(res *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: res.StatusCode,
+		Body:       body,
+		Message:    parseErrorMessage(body),
+		Header:     res.Header,
+	}
+	if res.Request != nil {
+		e.Method = res.Request.Method
+		e.Path = res.Request.URL.Path
+	}
+	for _, h := range requestIdHeaders {
+		if id := res.Header.Get(h); id != "" {
+			e.RequestId = id
+			break
+		}
+	}
+	return e
+}
+
+// parseErrorMessage extracts the error message from the different formats returned by the platform.
+func parseErrorMessage(body []byte) string {
+	var payload struct {
+		Message          string `json:"message"`
+		ErrorDescription string `json:"error_description"`
+		Error            string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+
+	switch {
+	case payload.Message != "":
+		return payload.Message
+	case payload.ErrorDescription != "":
+		return payload.ErrorDescription
+	default:
+		return payload.Error
+	}
+}
+
+// hasStatusCode returns true if err is an APIError with the given status code.
+func hasStatusCode(err error, statusCode int) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.StatusCode == statusCode
+}
+
+// IsBadRequest returns true if the platform rejected the request payload.
+func IsBadRequest(err error) bool {
+	return hasStatusCode(err, http.StatusBadRequest)
+}
+
+// IsUnauthorized returns true if the platform rejected the credentials or access token.
+func IsUnauthorized(err error) bool {
+	return hasStatusCode(err, http.StatusUnauthorized)
+}
+
+// IsForbidden returns true if the access token does not allow the operation.
+func IsForbidden(err error) bool {
+	return hasStatusCode(err, http.StatusForbidden)
+}
+
+// IsNotFound returns true if the requested entity does not exist.
+func IsNotFound(err error) bool {
+	return hasStatusCode(err, http.StatusNotFound)
+}
+
+// IsConflict returns true if the entity already exists or conflicts with another one.
+func IsConflict(err error) bool {
+	return hasStatusCode(err, http.StatusConflict)
+}
@@ -0,0 +1,95 @@
+package mnubo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAPIError(t *testing.T) {
+	cases := []struct {
+		StatusCode      int
+		ContentType     string
+		Body            string
+		ExpectedMessage string
+		Check           func(error) bool
+	}{
+		{
+			StatusCode:      http.StatusBadRequest,
+			ContentType:     "application/json",
+			Body:            `{"message":"x_device_id is missing"}`,
+			ExpectedMessage: "x_device_id is missing",
+			Check:           IsBadRequest,
+		},
+		{
+			StatusCode:      http.StatusNotFound,
+			ContentType:     "text/plain",
+			Body:            "Object with x_device_id 'abc' not found.\n",
+			ExpectedMessage: "Object with x_device_id 'abc' not found.",
+			Check:           IsNotFound,
+		},
+		{
+			StatusCode:      http.StatusConflict,
+			ContentType:     "application/json",
+			Body:            `{"error":"conflict","error_description":"Owner already exists"}`,
+			ExpectedMessage: "Owner already exists",
+			Check:           IsConflict,
+		},
+		{
+			StatusCode:      http.StatusForbidden,
+			ContentType:     "application/json",
+			Body:            `{"error":"access_denied"}`,
+			ExpectedMessage: "access_denied",
+			Check:           IsForbidden,
+		},
+	}
+
+	for i, c := range cases {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", c.ContentType)
+			w.Header().Set("X-Request-Id", "request-1")
+			w.WriteHeader(c.StatusCode)
+			w.Write([]byte(c.Body))
+		}))
+		m := NewClientWithToken("TOKEN", ts.URL)
+
+		err := m.Objects.Delete("abc")
+		ts.Close()
+
+		var apiError *APIError
+		if !errors.As(err, &apiError) {
+			t.Errorf("%d, expecting an APIError, got: %+v", i, err)
+			continue
+		}
+		if !c.Check(err) {
+			t.Errorf("%d, status check failed for: %+v", i, apiError)
+		}
+		if apiError.StatusCode != c.StatusCode || string(apiError.Body) != c.Body || apiError.Message != c.ExpectedMessage {
+			t.Errorf("%d, unexpected error content: %+v", i, apiError)
+		}
+		if apiError.Method != "DELETE" || apiError.Path != "/api/v3/objects/abc" || apiError.RequestId != "request-1" {
+			t.Errorf("%d, unexpected request information: %+v", i, apiError)
+		}
+		if IsUnauthorized(err) {
+			t.Errorf("%d, should not be unauthorized: %+v", i, apiError)
+		}
+	}
+}
+
+func TestAPIError_NotAvailable(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.ExponentialBackoff.MaxElapsedTime = time.Millisecond * 100
+
+	err := m.Objects.Delete("abc")
+
+	var apiError *APIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusServiceUnavailable || apiError.Message != "maintenance" {
+		t.Errorf("expecting the APIError of the last attempt, got: %+v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package mnubo
+
+// Path, Read and Write give the tests of package mnubo_test access to the files of a FileTokenCache.
+
+func (c *FileTokenCache) Path(host string, clientId string, scope string) string {
+	return c.path(host, clientId, scope)
+}
+
+func (c *FileTokenCache) Read(path string, previous string) (AccessToken, bool) {
+	return c.read(path, previous)
+}
+
+func (c *FileTokenCache) Write(path string, at AccessToken) error {
+	return c.write(path, at)
+}
@@ -1,9 +1,11 @@
 package mnubo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"reflect"
 )
 
 const (
@@ -88,7 +90,7 @@ func NewOwners(m *Mnubo) *Owners {
 }
 
 // buildEventsClientRequest is an internal function to help send events to SmartObjects.
-func buildEventsClientRequest(events interface{}, options SendEventsOptions, path string) (ClientRequest, error) {
+func buildEventsClientRequest(operation string, events interface{}, options SendEventsOptions, path string) (ClientRequest, error) {
 	bytes, err := json.Marshal(events)
 
 	if err != nil {
@@ -106,6 +108,8 @@ func buildEventsClientRequest(events interface{}, options SendEventsOptions, pat
 	}
 
 	return ClientRequest{
+		operation:   operation,
+		events:      countEvents(events),
 		method:      "POST",
 		contentType: "application/json",
 		path:        path,
@@ -114,34 +118,61 @@ func buildEventsClientRequest(events interface{}, options SendEventsOptions, pat
 	}, nil
 }
 
+// countEvents returns the number of events in a slice or array, or 0 if it is something else.
+func countEvents(events interface{}) int {
+	v := reflect.ValueOf(events)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		return v.Len()
+	}
+	return 0
+}
+
 // Send allows to post events to SmartObjects.
 // The events payload depends on the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#post-api-v3-events
 func (e *Events) Send(events interface{}, options SendEventsOptions, results interface{}) error {
-	cr, err := buildEventsClientRequest(events, options, eventsPath)
+	return e.SendContext(context.Background(), events, options, results)
+}
+
+// SendContext is like Send but uses ctx to cancel the request and its retries.
+func (e *Events) SendContext(ctx context.Context, events interface{}, options SendEventsOptions, results interface{}) error {
+	cr, err := buildEventsClientRequest("Events.Send", events, options, eventsPath)
 
 	if err != nil {
 		return err
 	}
 
-	return e.Mnubo.doRequestWithAuthentication(cr, results)
+	return e.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // SendFromDevice allows to post events to SmartObjects from one device.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#post-api-v3-objects-x-device-id-events
 func (e *Events) SendFromDevice(deviceId string, events interface{}, options SendEventsOptions, results interface{}) error {
-	cr, err := buildEventsClientRequest(events, options, fmt.Sprintf("%s/%s/events", objectsPath, deviceId))
+	return e.SendFromDeviceContext(context.Background(), deviceId, events, options, results)
+}
+
+// SendFromDeviceContext is like SendFromDevice but uses ctx to cancel the request and its retries.
+func (e *Events) SendFromDeviceContext(ctx context.Context, deviceId string, events interface{}, options SendEventsOptions, results interface{}) error {
+	cr, err := buildEventsClientRequest("Events.SendFromDevice", events, options, fmt.Sprintf("%s/%s/events", objectsPath, deviceId))
 
 	if err != nil {
 		return err
 	}
 
-	return e.Mnubo.doRequestWithAuthentication(cr, results)
+	return e.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // Exists checks if an event has already been submitted.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#post-api-v3-events-exists
 func (e *Events) Exists(eventIds []string, results *EntitiesExist) error {
+	return e.ExistsContext(context.Background(), eventIds, results)
+}
+
+// ExistsContext is like Exists but uses ctx to cancel the request and its retries.
+func (e *Events) ExistsContext(ctx context.Context, eventIds []string, results *EntitiesExist) error {
 	if *results == nil {
 		res := make(EntitiesExist)
 		results = &res
@@ -153,15 +184,17 @@ func (e *Events) Exists(eventIds []string, results *EntitiesExist) error {
 	}
 
 	cr := ClientRequest{
+		operation:   "Events.Exists",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/exists", eventsPath),
 		payload:     bytes,
+		idempotent:  true,
 	}
 
 	rawResults := []map[string]bool{}
 	// this endpoint returns an array of objects
-	err = e.Mnubo.doRequestWithAuthentication(cr, &rawResults)
+	err = e.Mnubo.doRequestWithAuthentication(ctx, cr, &rawResults)
 	if err != nil {
 		return err
 	}
@@ -180,6 +213,11 @@ func (e *Events) Exists(eventIds []string, results *EntitiesExist) error {
 // The objects payload is based on the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#post-api-v3-objects
 func (o *Objects) Create(objects interface{}, results interface{}) error {
+	return o.CreateContext(context.Background(), objects, results)
+}
+
+// CreateContext is like Create but uses ctx to cancel the request and its retries.
+func (o *Objects) CreateContext(ctx context.Context, objects interface{}, results interface{}) error {
 	bytes, err := json.Marshal(objects)
 
 	if err != nil {
@@ -187,18 +225,24 @@ func (o *Objects) Create(objects interface{}, results interface{}) error {
 	}
 
 	cr := ClientRequest{
+		operation:   "Objects.Create",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s", objectsPath),
 		payload:     bytes,
 	}
 
-	return o.Mnubo.doRequestWithAuthentication(cr, results)
+	return o.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // Update creates and / or updates a batch of objects at once.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#put-api-v3-objects-batch
 func (o *Objects) Update(objects interface{}, results interface{}) error {
+	return o.UpdateContext(context.Background(), objects, results)
+}
+
+// UpdateContext is like Update but uses ctx to cancel the request and its retries.
+func (o *Objects) UpdateContext(ctx context.Context, objects interface{}, results interface{}) error {
 	bytes, err := json.Marshal(objects)
 
 	if err != nil {
@@ -206,30 +250,42 @@ func (o *Objects) Update(objects interface{}, results interface{}) error {
 	}
 
 	cr := ClientRequest{
+		operation:   "Objects.Update",
 		method:      "PUT",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s", objectsPath),
 		payload:     bytes,
 	}
 
-	return o.Mnubo.doRequestWithAuthentication(cr, results)
+	return o.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // Delete deletes an object
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#delete-api-v3-objects-x-device-id
 func (o *Objects) Delete(deviceId string) error {
+	return o.DeleteContext(context.Background(), deviceId)
+}
+
+// DeleteContext is like Delete but uses ctx to cancel the request and its retries.
+func (o *Objects) DeleteContext(ctx context.Context, deviceId string) error {
 	cr := ClientRequest{
-		method: "DELETE",
-		path:   fmt.Sprintf("%s/%s", objectsPath, deviceId),
+		operation: "Objects.Delete",
+		method:    "DELETE",
+		path:      fmt.Sprintf("%s/%s", objectsPath, deviceId),
 	}
 
 	var results interface{}
-	return o.Mnubo.doRequestWithAuthentication(cr, &results)
+	return o.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // Exist checks if an array of objects have been created.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#post-api-v3-objects-exists
 func (o *Objects) Exist(deviceIds []string, results *EntitiesExist) error {
+	return o.ExistContext(context.Background(), deviceIds, results)
+}
+
+// ExistContext is like Exist but uses ctx to cancel the request and its retries.
+func (o *Objects) ExistContext(ctx context.Context, deviceIds []string, results *EntitiesExist) error {
 	if *results == nil {
 		res := make(EntitiesExist)
 		results = &res
@@ -241,15 +297,17 @@ func (o *Objects) Exist(deviceIds []string, results *EntitiesExist) error {
 	}
 
 	cr := ClientRequest{
+		operation:   "Objects.Exist",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/exists", objectsPath),
 		payload:     bytes,
+		idempotent:  true,
 	}
 
 	rawResults := []map[string]bool{}
 	// this endpoint returns an array of objects
-	err = o.Mnubo.doRequestWithAuthentication(cr, &rawResults)
+	err = o.Mnubo.doRequestWithAuthentication(ctx, cr, &rawResults)
 	if err != nil {
 		return err
 	}
@@ -268,6 +326,11 @@ func (o *Objects) Exist(deviceIds []string, results *EntitiesExist) error {
 // The owner payload is based on the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#post-api-v3-owners
 func (o *Owners) Create(owners interface{}, results interface{}) error {
+	return o.CreateContext(context.Background(), owners, results)
+}
+
+// CreateContext is like Create but uses ctx to cancel the request and its retries.
+func (o *Owners) CreateContext(ctx context.Context, owners interface{}, results interface{}) error {
 	bytes, err := json.Marshal(owners)
 
 	if err != nil {
@@ -275,18 +338,24 @@ func (o *Owners) Create(owners interface{}, results interface{}) error {
 	}
 
 	cr := ClientRequest{
+		operation:   "Owners.Create",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s", ownersPath),
 		payload:     bytes,
 	}
 
-	return o.Mnubo.doRequestWithAuthentication(cr, results)
+	return o.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // Update creates and / or updates a batch of owners at once.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#put-api-v3-owners-batch
 func (o *Owners) Update(owners interface{}, results interface{}) error {
+	return o.UpdateContext(context.Background(), owners, results)
+}
+
+// UpdateContext is like Update but uses ctx to cancel the request and its retries.
+func (o *Owners) UpdateContext(ctx context.Context, owners interface{}, results interface{}) error {
 	bytes, err := json.Marshal(owners)
 
 	if err != nil {
@@ -294,18 +363,24 @@ func (o *Owners) Update(owners interface{}, results interface{}) error {
 	}
 
 	cr := ClientRequest{
+		operation:   "Owners.Update",
 		method:      "PUT",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s", ownersPath),
 		payload:     bytes,
 	}
 
-	return o.Mnubo.doRequestWithAuthentication(cr, results)
+	return o.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // UpdateOwnerPassword updates an owner password.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#put-api-v3-owners-username-password
 func (o *Owners) UpdateOwnerPassword(username string, password string) error {
+	return o.UpdateOwnerPasswordContext(context.Background(), username, password)
+}
+
+// UpdateOwnerPasswordContext is like UpdateOwnerPassword but uses ctx to cancel the request and its retries.
+func (o *Owners) UpdateOwnerPasswordContext(ctx context.Context, username string, password string) error {
 	bytes, err := json.Marshal(PasswordUpdatePayload{
 		XPassword: password,
 	})
@@ -314,6 +389,7 @@ func (o *Owners) UpdateOwnerPassword(username string, password string) error {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Owners.UpdateOwnerPassword",
 		method:      "PUT",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/%s/password", ownersPath, username),
@@ -321,24 +397,35 @@ func (o *Owners) UpdateOwnerPassword(username string, password string) error {
 	}
 
 	var results interface{}
-	return o.Mnubo.doRequestWithAuthentication(cr, &results)
+	return o.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // Delete deletes an owner from SmartObjects.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#delete-api-v3-owners-username
 func (o *Owners) Delete(username string) error {
+	return o.DeleteContext(context.Background(), username)
+}
+
+// DeleteContext is like Delete but uses ctx to cancel the request and its retries.
+func (o *Owners) DeleteContext(ctx context.Context, username string) error {
 	cr := ClientRequest{
-		method: "DELETE",
-		path:   fmt.Sprintf("%s/%s", ownersPath, username),
+		operation: "Owners.Delete",
+		method:    "DELETE",
+		path:      fmt.Sprintf("%s/%s", ownersPath, username),
 	}
 
 	var results interface{}
-	return o.Mnubo.doRequestWithAuthentication(cr, &results)
+	return o.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // Exist checks if an array of owners exist in SmartObjects.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#get-api-v3-owners-exists-username
 func (o *Owners) Exist(usernames []string, results *EntitiesExist) error {
+	return o.ExistContext(context.Background(), usernames, results)
+}
+
+// ExistContext is like Exist but uses ctx to cancel the request and its retries.
+func (o *Owners) ExistContext(ctx context.Context, usernames []string, results *EntitiesExist) error {
 	// Check if results was nil
 	// Covers cases where the user create the results object with something like
 	// `var results EntitiesExist`
@@ -354,15 +441,17 @@ func (o *Owners) Exist(usernames []string, results *EntitiesExist) error {
 	}
 
 	cr := ClientRequest{
+		operation:   "Owners.Exist",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/exists", ownersPath),
 		payload:     bytes,
+		idempotent:  true,
 	}
 
 	rawResults := []map[string]bool{}
 	// this endpoint returns an array of objects
-	err = o.Mnubo.doRequestWithAuthentication(cr, &rawResults)
+	err = o.Mnubo.doRequestWithAuthentication(ctx, cr, &rawResults)
 	if err != nil {
 		return err
 	}
@@ -380,6 +469,11 @@ func (o *Owners) Exist(usernames []string, results *EntitiesExist) error {
 // Claim claims an array of object / owner pair.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#post-api-v3-owners-claim-batch
 func (o *Owners) Claim(pairs []ObjectOwnerPair, results *[]ClaimResult) error {
+	return o.ClaimContext(context.Background(), pairs, results)
+}
+
+// ClaimContext is like Claim but uses ctx to cancel the request and its retries.
+func (o *Owners) ClaimContext(ctx context.Context, pairs []ObjectOwnerPair, results *[]ClaimResult) error {
 	bytes, err := json.Marshal(pairs)
 
 	if err != nil {
@@ -387,18 +481,24 @@ func (o *Owners) Claim(pairs []ObjectOwnerPair, results *[]ClaimResult) error {
 	}
 
 	cr := ClientRequest{
+		operation:   "Owners.Claim",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/claim", ownersPath),
 		payload:     bytes,
 	}
 
-	return o.Mnubo.doRequestWithAuthentication(cr, results)
+	return o.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // Unclaim unclaims an array of object / owner pair.
 // See: https://smartobjects.mnubo.com/documentation/api_ingestion.html#post-api-v3-owners-unclaim-batch
 func (o *Owners) Unclaim(pairs []ObjectOwnerPair, results *[]ClaimResult) error {
+	return o.UnclaimContext(context.Background(), pairs, results)
+}
+
+// UnclaimContext is like Unclaim but uses ctx to cancel the request and its retries.
+func (o *Owners) UnclaimContext(ctx context.Context, pairs []ObjectOwnerPair, results *[]ClaimResult) error {
 	bytes, err := json.Marshal(pairs)
 
 	if err != nil {
@@ -406,11 +506,12 @@ func (o *Owners) Unclaim(pairs []ObjectOwnerPair, results *[]ClaimResult) error
 	}
 
 	cr := ClientRequest{
+		operation:   "Owners.Unclaim",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/unclaim", ownersPath),
 		payload:     bytes,
 	}
 
-	return o.Mnubo.doRequestWithAuthentication(cr, results)
+	return o.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
@@ -359,3 +359,22 @@ func TestOwners_Unclaim(t *testing.T) {
 		t.Errorf("client call failed: %+v", err)
 	}
 }
+
+func TestCountEvents(t *testing.T) {
+	events := []map[string]string{{}, {}}
+	cases := []struct {
+		Events   interface{}
+		Expected int
+	}{
+		{events, 2},
+		{&events, 2},
+		{[1]string{"event"}, 1},
+		{`[{"x_event_type": "type"}]`, 0},
+		{nil, 0},
+	}
+	for i, c := range cases {
+		if got := countEvents(c.Events); got != c.Expected {
+			t.Errorf("%d, expecting: %d, got: %d", i, c.Expected, got)
+		}
+	}
+}
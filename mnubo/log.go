@@ -0,0 +1,195 @@
+package mnubo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the secrets removed from logs.
+const Redacted = "REDACTED"
+
+// LogConfig controls what the client logs with Mnubo.Logger.
+type LogConfig struct {
+	// Headers adds the request headers to the debug logs.
+	Headers bool
+	// Payloads adds the uncompressed request payloads to the debug logs.
+	Payloads bool
+	// Redaction lists the secrets removed from logs.
+	Redaction Redaction
+}
+
+// Redaction lists the secrets replaced by REDACTED in logs.
+type Redaction struct {
+	// Authorization redacts the Authorization header, keeping its scheme.
+	Authorization bool
+	// ClientSecret redacts the secret of Basic credentials and Mnubo.ClientSecret anywhere in logs.
+	ClientSecret bool
+	// OwnerPassword redacts x_password in payloads, sent by Owners.Create and Owners.UpdateOwnerPassword.
+	OwnerPassword bool
+	// Fields are other JSON fields redacted from payloads, at any depth.
+	Fields []string
+}
+
+// DefaultLogConfig logs neither headers nor payloads, and redacts all secrets if they are enabled.
+func DefaultLogConfig() LogConfig {
+	return LogConfig{
+		Redaction: Redaction{
+			Authorization: true,
+			ClientSecret:  true,
+			OwnerPassword: true,
+		},
+	}
+}
+
+// requestLogger logs the HTTP requests of an operation.
+type requestLogger struct {
+	logger *slog.Logger
+	config LogConfig
+	secret string
+}
+
+// newRequestLogger returns the logger of the client, or nil if it has none.
+func (m *Mnubo) newRequestLogger() *requestLogger {
+	if m.Logger == nil {
+		return nil
+	}
+	return &requestLogger{
+		logger: m.Logger,
+		config: m.LogConfig,
+		secret: m.ClientSecret,
+	}
+}
+
+// logAttempt logs an HTTP request sent for op at debug level, or at warn level if it failed.
+func (l *requestLogger) logAttempt(ctx context.Context, op *operation, req *http.Request, attempt int, start time.Time, res *http.Response, err error) {
+	if l == nil {
+		return
+	}
+
+	level := slog.LevelDebug
+	if err != nil || res == nil || res.StatusCode >= http.StatusBadRequest {
+		level = slog.LevelWarn
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("operation", op.name),
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.Duration("duration", time.Since(start)),
+		slog.Int("attempt", attempt),
+		slog.Int64("size", req.ContentLength),
+	}
+	if res != nil {
+		attrs = append(attrs, slog.Int("status", res.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", l.redactSecret(err.Error())))
+	}
+	if l.config.Headers {
+		attrs = append(attrs, slog.Any("headers", l.redactHeader(req.Header)))
+	}
+	if l.config.Payloads && len(op.payload) > 0 {
+		attrs = append(attrs, slog.String("payload", l.redactPayload(op.payload)))
+	}
+
+	msg := "SmartObjects request"
+	if level == slog.LevelWarn {
+		msg = "SmartObjects request failed"
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// redactSecret replaces the client secret in s.
+func (l *requestLogger) redactSecret(s string) string {
+	if !l.config.Redaction.ClientSecret || l.secret == "" {
+		return s
+	}
+	return strings.Replace(s, l.secret, Redacted, -1)
+}
+
+// redactHeader returns a copy of h without the secrets it may contain.
+func (l *requestLogger) redactHeader(h http.Header) http.Header {
+	redacted := h.Clone()
+	for i, v := range redacted.Values("Authorization") {
+		scheme := v
+		if sp := strings.IndexByte(v, ' '); sp >= 0 {
+			scheme = v[:sp]
+		}
+		switch {
+		case l.config.Redaction.Authorization:
+			v = scheme + " " + Redacted
+		case l.config.Redaction.ClientSecret && strings.EqualFold(scheme, "Basic"):
+			v = scheme + " " + redactBasicCredentials(strings.TrimSpace(v[len(scheme):]))
+		}
+		redacted["Authorization"][i] = v
+	}
+	for k, values := range redacted {
+		for i := range values {
+			values[i] = l.redactSecret(values[i])
+		}
+		redacted[k] = values
+	}
+	return redacted
+}
+
+// redactBasicCredentials replaces the secret of base64 encoded id:secret credentials.
+func redactBasicCredentials(credentials string) string {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return Redacted
+	}
+	id := string(decoded)
+	if i := strings.IndexByte(id, ':'); i >= 0 {
+		id = id[:i]
+	}
+	return base64.StdEncoding.EncodeToString([]byte(id + ":" + Redacted))
+}
+
+// redactPayload returns payload as a string without the secrets it may contain.
+// Payloads which are not JSON only get the client secret redacted.
+func (l *requestLogger) redactPayload(payload []byte) string {
+	fields := map[string]bool{}
+	if l.config.Redaction.OwnerPassword {
+		fields["x_password"] = true
+	}
+	for _, f := range l.config.Redaction.Fields {
+		fields[f] = true
+	}
+
+	var v interface{}
+	if len(fields) == 0 || json.Unmarshal(payload, &v) != nil {
+		return l.redactSecret(string(payload))
+	}
+	redacted, err := json.Marshal(redactFields(v, fields))
+	if err != nil {
+		return l.redactSecret(string(payload))
+	}
+	return l.redactSecret(string(redacted))
+}
+
+// redactFields replaces the values of fields in a decoded JSON value, at any depth.
+func redactFields(v interface{}, fields map[string]bool) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if fields[k] {
+				v[k] = Redacted
+			} else {
+				v[k] = redactFields(e, fields)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = redactFields(e, fields)
+		}
+	}
+	return v
+}
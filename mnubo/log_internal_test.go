@@ -0,0 +1,39 @@
+package mnubo
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+func TestRedaction(t *testing.T) {
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("CLIENT_ID:CLIENT_SECRET"))
+	cases := []struct {
+		Redaction     Redaction
+		Authorization string
+		Payload       string
+		Expected      string
+		ExpectedAuth  string
+	}{
+		{DefaultLogConfig().Redaction, basic, `{"x_password":"pass"}`, `{"x_password":"REDACTED"}`, "Basic REDACTED"},
+		{Redaction{ClientSecret: true}, basic, `{"x_password":"pass"}`, `{"x_password":"pass"}`, "Basic " + base64.StdEncoding.EncodeToString([]byte("CLIENT_ID:REDACTED"))},
+		{Redaction{}, "Bearer TOKEN", `[{"owner":{"x_password":"pass"}}]`, `[{"owner":{"x_password":"pass"}}]`, "Bearer TOKEN"},
+		{Redaction{OwnerPassword: true}, "Bearer TOKEN", `[{"owner":{"x_password":"pass"}}]`, `[{"owner":{"x_password":"REDACTED"}}]`, "Bearer TOKEN"},
+		{Redaction{Fields: []string{"serial"}}, "Bearer TOKEN", `{"serial":"1234","x_password":"pass"}`, `{"serial":"REDACTED","x_password":"pass"}`, "Bearer TOKEN"},
+		{Redaction{ClientSecret: true}, "Bearer TOKEN", `secret=CLIENT_SECRET`, `secret=REDACTED`, "Bearer TOKEN"},
+	}
+	for i, c := range cases {
+		l := &requestLogger{config: LogConfig{Redaction: c.Redaction}, secret: "CLIENT_SECRET"}
+
+		if got := l.redactPayload([]byte(c.Payload)); got != c.Expected {
+			t.Errorf("%d, expecting: %s, got: %s", i, c.Expected, got)
+		}
+		h := http.Header{"Authorization": {c.Authorization}}
+		if got := l.redactHeader(h).Get("Authorization"); got != c.ExpectedAuth {
+			t.Errorf("%d, expecting: %s, got: %s", i, c.ExpectedAuth, got)
+		}
+		if h.Get("Authorization") != c.Authorization {
+			t.Errorf("%d, expecting request headers to be unchanged, got: %s", i, h.Get("Authorization"))
+		}
+	}
+}
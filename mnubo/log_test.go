@@ -0,0 +1,146 @@
+package mnubo_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+	"github.com/mnubo/smartobjects-go-client/mnubo/mnubotest"
+)
+
+// newLogServer starts a server storing the owner whose password is updated by the tests.
+func newLogServer(t *testing.T) *mnubotest.Server {
+	srv := mnubotest.NewServer()
+	m, err := srv.NewClient()
+	if err != nil {
+		t.Fatalf("unable to create client: %+v", err)
+	}
+	if err := m.Owners.Create(map[string]string{"username": "owner", "x_password": "PASSWORD"}, &map[string]interface{}{}); err != nil {
+		t.Fatalf("unable to create owner: %+v", err)
+	}
+	return srv
+}
+
+func newLogClient(t *testing.T, srv *mnubotest.Server) (*mnubo.Mnubo, *bytes.Buffer) {
+	var buf bytes.Buffer
+	config := mnubo.DefaultLogConfig()
+	config.Headers = true
+	config.Payloads = true
+	m, err := srv.NewClient(mnubo.WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})), config))
+	if err != nil {
+		t.Fatalf("unable to create client: %+v", err)
+	}
+	return m, &buf
+}
+
+func parseLogs(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %s: %+v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLogger(t *testing.T) {
+	srv := newLogServer(t)
+	defer srv.Close()
+	m, buf := newLogClient(t, srv)
+	if _, err := m.GetAccessToken(); err != nil {
+		t.Fatalf("unable to get token: %+v", err)
+	}
+	srv.Inject(mnubotest.ServiceUnavailable(1))
+
+	err := m.Owners.UpdateOwnerPassword("owner", "OWNER_PASSWORD")
+
+	if err != nil {
+		t.Fatalf("client call failed: %+v", err)
+	}
+	secrets := []string{
+		srv.ClientSecret,
+		"OWNER_PASSWORD",
+		m.CachedAccessToken("").Value,
+		base64.StdEncoding.EncodeToString([]byte(srv.ClientID + ":" + srv.ClientSecret)),
+	}
+	for _, secret := range secrets {
+		if strings.Contains(buf.String(), secret) {
+			t.Errorf("expecting %s to be redacted, got: %s", secret, buf.String())
+		}
+	}
+
+	entries := parseLogs(t, buf)
+	cases := []struct {
+		Level     string
+		Operation string
+		Path      string
+		Status    float64
+		Attempt   float64
+	}{
+		{"DEBUG", "Mnubo.GetAccessToken", "/oauth/token", 200, 1},
+		{"WARN", "Owners.UpdateOwnerPassword", "/api/v3/owners/owner/password", 503, 1},
+		{"DEBUG", "Owners.UpdateOwnerPassword", "/api/v3/owners/owner/password", 200, 2},
+	}
+	if len(entries) != len(cases) {
+		t.Fatalf("expecting %d log entries, got: %s", len(cases), buf.String())
+	}
+	for i, c := range cases {
+		e := entries[i]
+		if e["level"] != c.Level || e["operation"] != c.Operation || e["path"] != c.Path || e["status"] != c.Status || e["attempt"] != c.Attempt {
+			t.Errorf("%d, expecting: %+v, got: %+v", i, c, e)
+		}
+		if _, ok := e["duration"]; !ok {
+			t.Errorf("%d, expecting duration, got: %+v", i, e)
+		}
+	}
+
+	headers := entries[2]["headers"].(map[string]interface{})
+	if auth := headers["Authorization"].([]interface{})[0]; auth != "Bearer REDACTED" {
+		t.Errorf("expecting redacted authorization, got: %s", auth)
+	}
+	if payload := entries[2]["payload"]; payload != `{"x_password":"REDACTED"}` {
+		t.Errorf("expecting redacted password, got: %s", payload)
+	}
+}
+
+func TestLogger_LevelAndDefaults(t *testing.T) {
+	srv := newLogServer(t)
+	defer srv.Close()
+	var buf bytes.Buffer
+	m, err := mnubo.New(
+		mnubo.WithHost(srv.URL),
+		mnubo.WithToken(srv.IssueToken()),
+		mnubo.WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})), mnubo.DefaultLogConfig()),
+	)
+	if err != nil {
+		t.Fatalf("unable to create client: %+v", err)
+	}
+
+	if err := m.Owners.UpdateOwnerPassword("owner", "OWNER_PASSWORD"); err != nil {
+		t.Fatalf("client call failed: %+v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expecting successful requests to be logged at debug level, got: %s", buf.String())
+	}
+
+	m.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if err := m.Owners.UpdateOwnerPassword("owner", "OWNER_PASSWORD"); err != nil {
+		t.Fatalf("client call failed: %+v", err)
+	}
+	entry := parseLogs(t, &buf)[0]
+	if _, ok := entry["headers"]; ok {
+		t.Errorf("expecting no headers by default, got: %+v", entry)
+	}
+	if _, ok := entry["payload"]; ok {
+		t.Errorf("expecting no payload by default, got: %+v", entry)
+	}
+	if entry["size"] != float64(len(`{"x_password":"OWNER_PASSWORD"}`)) {
+		t.Errorf("expecting payload size, got: %+v", entry)
+	}
+}
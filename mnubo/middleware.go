@@ -0,0 +1,79 @@
+package mnubo
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Handler sends a request to SmartObjects and decodes its response into response.
+type Handler func(ctx context.Context, cr *ClientRequest, response interface{}) error
+
+// Middleware wraps a Handler to act before and / or after requests are sent.
+// A middleware can change the request, skip it by not calling next, or inspect the
+// response and error returned by next. Retries happen inside next.
+type Middleware func(next Handler) Handler
+
+// Operation returns the name of the client method sending the request, like "Events.Send".
+func (cr *ClientRequest) Operation() string {
+	return cr.operation
+}
+
+// Method returns the HTTP method of the request.
+func (cr *ClientRequest) Method() string {
+	return cr.method
+}
+
+// Path returns the path of the request, relative to the client Host.
+func (cr *ClientRequest) Path() string {
+	return cr.path
+}
+
+// ContentType returns the content type of the payload.
+func (cr *ClientRequest) ContentType() string {
+	return cr.contentType
+}
+
+// Authorization returns the value of the Authorization header.
+func (cr *ClientRequest) Authorization() string {
+	return cr.authorization
+}
+
+// Query returns the query string parameters of the request, which can be modified.
+func (cr *ClientRequest) Query() url.Values {
+	if cr.urlQuery == nil {
+		cr.urlQuery = url.Values{}
+	}
+	return cr.urlQuery
+}
+
+// Header returns additional headers to send with the request, which can be modified.
+// Headers set by the client, like Authorization or Content-Type, have precedence.
+func (cr *ClientRequest) Header() http.Header {
+	if cr.header == nil {
+		cr.header = http.Header{}
+	}
+	return cr.header
+}
+
+// Payload returns the uncompressed payload of the request.
+func (cr *ClientRequest) Payload() []byte {
+	return cr.payload
+}
+
+// SetPayload replaces the payload of the request.
+func (cr *ClientRequest) SetPayload(payload []byte) {
+	cr.payload = payload
+}
+
+// HeaderMiddleware adds headers to every request, for instance to identify a tenant.
+func HeaderMiddleware(header http.Header) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, cr *ClientRequest, response interface{}) error {
+			for k, v := range header {
+				cr.Header()[k] = v
+			}
+			return next(ctx, cr, response)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package mnubo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewares(t *testing.T) {
+	var received *http.Request
+	var receivedBody []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(r.Body)
+		receivedBody = buf.Bytes()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	var calls []string
+	audit := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, cr *ClientRequest, response interface{}) error {
+				calls = append(calls, name+" before "+cr.Method()+" "+cr.Path())
+				err := next(ctx, cr, response)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+	redact := func(next Handler) Handler {
+		return func(ctx context.Context, cr *ClientRequest, response interface{}) error {
+			cr.SetPayload(bytes.Replace(cr.Payload(), []byte("secret"), []byte("******"), -1))
+			cr.Query().Set("tenant", "tenant-1")
+			return next(ctx, cr, response)
+		}
+	}
+	m.Middlewares = []Middleware{
+		audit("first"),
+		audit("second"),
+		redact,
+		HeaderMiddleware(http.Header{"X-Tenant": {"tenant-1"}, "Authorization": {"overridden"}}),
+	}
+
+	err := m.Owners.UpdateOwnerPassword("owner", "secret")
+
+	if err != nil {
+		t.Fatalf("client call failed: %+v", err)
+	}
+	expected := []string{
+		"first before PUT /api/v3/owners/owner/password",
+		"second before PUT /api/v3/owners/owner/password",
+		"second after",
+		"first after",
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("expecting calls: %+v, got: %+v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("%d, expecting: %s, got: %s", i, expected[i], calls[i])
+		}
+	}
+	if string(receivedBody) != `{"x_password":"******"}` {
+		t.Errorf("expecting payload to be redacted, got: %s", receivedBody)
+	}
+	if received.Header.Get("X-Tenant") != "tenant-1" || received.URL.Query().Get("tenant") != "tenant-1" {
+		t.Errorf("expecting tenant header and query, got: %+v", received)
+	}
+	if received.Header.Get("Authorization") != "Bearer TOKEN" {
+		t.Errorf("client headers should have precedence, got: %s", received.Header.Get("Authorization"))
+	}
+}
+
+func TestMiddlewares_FaultInjection(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	injected := errors.New("injected failure")
+	m.Middlewares = []Middleware{
+		func(next Handler) Handler {
+			return func(ctx context.Context, cr *ClientRequest, response interface{}) error {
+				return injected
+			}
+		},
+	}
+
+	err := m.Objects.Delete("device")
+
+	if !errors.Is(err, injected) || requests != 0 {
+		t.Errorf("expecting the injected failure without sending the request, got: %+v after %d requests", err, requests)
+	}
+}
@@ -0,0 +1,194 @@
+// Package mnubootel traces the calls of SmartObjects clients and records their metrics with OpenTelemetry.
+//
+// It is a separate package so that clients not using OpenTelemetry don't depend on it:
+//
+//	m, err := mnubo.New(
+//		mnubo.WithHost("YOUR_HOST_URL"),
+//		mnubo.WithClientCredentials("YOUR_CLIENT_ID", "YOUR_CLIENT_SECRET"),
+//		mnubo.WithObservers(mnubootel.NewObserver(otel.GetTracerProvider(), otel.GetMeterProvider())),
+//	)
+package mnubootel
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	instrumentationName = "github.com/mnubo/smartobjects-go-client/mnubo/mnubootel"
+
+	attributeOperation   = attribute.Key("mnubo.operation")
+	attributeAttempt     = attribute.Key("mnubo.attempt")
+	attributeAttempts    = attribute.Key("mnubo.attempts")
+	attributePayloadSize = attribute.Key("mnubo.payload.size")
+	attributeCompressed  = attribute.Key("mnubo.compressed")
+	attributeCompression = attribute.Key("mnubo.compression.ratio")
+	attributeEvents      = attribute.Key("mnubo.events")
+	attributeMethod      = attribute.Key("http.request.method")
+	attributePath        = attribute.Key("url.path")
+	attributeStatusCode  = attribute.Key("http.response.status_code")
+	attributeRequestSize = attribute.Key("http.request.body.size")
+	attributeErrorType   = attribute.Key("error.type")
+)
+
+// Observer is a mnubo.Observer tracing each client call and its HTTP requests, and recording
+// latency, error, retry and payload size metrics.
+type Observer struct {
+	tracer      trace.Tracer
+	duration    metric.Float64Histogram
+	errors      metric.Int64Counter
+	attempts    metric.Int64Counter
+	retries     metric.Int64Counter
+	payloadSize metric.Int64Histogram
+}
+
+// NewObserver creates the tracer and instruments from the given providers.
+// Traces or metrics are not recorded if their provider is nil.
+func NewObserver(tp trace.TracerProvider, mp metric.MeterProvider) *Observer {
+	if tp == nil {
+		tp = tracenoop.NewTracerProvider()
+	}
+	if mp == nil {
+		mp = metricnoop.NewMeterProvider()
+	}
+	meter := mp.Meter(instrumentationName)
+
+	// Instruments creation only fails with invalid names, a no-op instrument is returned in that case.
+	o := &Observer{
+		tracer: tp.Tracer(instrumentationName),
+	}
+	o.duration, _ = meter.Float64Histogram("mnubo.client.operation.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of client calls, including retries."))
+	o.errors, _ = meter.Int64Counter("mnubo.client.operation.errors",
+		metric.WithDescription("Number of client calls which failed."))
+	o.attempts, _ = meter.Int64Counter("mnubo.client.attempts",
+		metric.WithDescription("Number of HTTP requests sent to SmartObjects, by status code."))
+	o.retries, _ = meter.Int64Counter("mnubo.client.retries",
+		metric.WithDescription("Number of HTTP requests retried by the retry policy."))
+	o.payloadSize, _ = meter.Int64Histogram("mnubo.client.request.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of the request payloads, before and after compression."))
+	return o
+}
+
+// StartOperation implements mnubo.Observer, it starts the span of a client call.
+func (o *Observer) StartOperation(ctx context.Context, info mnubo.OperationInfo) (context.Context, mnubo.OperationObserver) {
+	ctx, span := o.tracer.Start(ctx, info.Name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attributeOperation.String(info.Name),
+			attributeMethod.String(info.Method),
+			attributePath.String(info.Path),
+		))
+	if info.Events > 0 {
+		span.SetAttributes(attributeEvents.Int(info.Events))
+	}
+	return ctx, &operation{
+		o:     o,
+		name:  info.Name,
+		span:  span,
+		start: time.Now(),
+	}
+}
+
+// ObserveTokenRefresh implements mnubo.Observer, token requests are traced as client calls.
+func (o *Observer) ObserveTokenRefresh(err error) {}
+
+// operation is the span and metrics of a client call.
+type operation struct {
+	o     *Observer
+	name  string
+	span  trace.Span
+	start time.Time
+}
+
+// ObservePayload records a request payload size, before and after compression.
+func (op *operation) ObservePayload(size int, sentSize int) {
+	ctx := context.Background()
+	op.span.SetAttributes(attributePayloadSize.Int(size), attributeRequestSize.Int(sentSize))
+	if sentSize > 0 && sentSize != size {
+		op.span.SetAttributes(attributeCompression.Float64(float64(size) / float64(sentSize)))
+	}
+	op.o.payloadSize.Record(ctx, int64(size), metric.WithAttributes(attributeOperation.String(op.name), attributeCompressed.Bool(false)))
+	op.o.payloadSize.Record(ctx, int64(sentSize), metric.WithAttributes(attributeOperation.String(op.name), attributeCompressed.Bool(true)))
+}
+
+// StartAttempt starts the span of one HTTP request sent for the call, and counts the retries.
+func (op *operation) StartAttempt(ctx context.Context, req *http.Request, attempt int) (context.Context, func(*http.Response, error)) {
+	ctx, span := op.o.tracer.Start(ctx, "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attributeMethod.String(req.Method),
+			attributePath.String(req.URL.Path),
+			attributeAttempt.Int(attempt),
+			attributeRequestSize.Int64(req.ContentLength),
+		))
+	if attempt > 1 {
+		op.o.retries.Add(ctx, 1, metric.WithAttributes(attributeOperation.String(op.name)))
+	}
+
+	return ctx, func(res *http.Response, err error) {
+		attrs := []attribute.KeyValue{attributeOperation.String(op.name)}
+		if res != nil {
+			span.SetAttributes(attributeStatusCode.Int(res.StatusCode))
+			attrs = append(attrs, attributeStatusCode.Int(res.StatusCode))
+			if res.StatusCode >= http.StatusBadRequest {
+				span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+			}
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			attrs = append(attrs, attributeErrorType.String(errorType(err)))
+		}
+		op.o.attempts.Add(context.Background(), 1, metric.WithAttributes(attrs...))
+		span.End()
+	}
+}
+
+// End records the outcome of the client call.
+func (op *operation) End(attempts int, err error) {
+	ctx := context.Background()
+	attrs := []attribute.KeyValue{attributeOperation.String(op.name)}
+
+	op.span.SetAttributes(attributeAttempts.Int(attempts))
+
+	if err != nil {
+		attrs = append(attrs, attributeErrorType.String(errorType(err)))
+		op.o.errors.Add(ctx, 1, metric.WithAttributes(attrs...))
+		op.span.RecordError(err)
+		op.span.SetStatus(codes.Error, err.Error())
+	}
+	op.o.duration.Record(ctx, time.Since(op.start).Seconds(), metric.WithAttributes(attrs...))
+	op.span.End()
+}
+
+// errorType returns a low cardinality description of err for metrics.
+func errorType(err error) string {
+	var apiError *mnubo.APIError
+	var netError net.Error
+	switch {
+	case errors.As(err, &apiError):
+		return http.StatusText(apiError.StatusCode)
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &netError):
+		return "network"
+	default:
+		return "other"
+	}
+}
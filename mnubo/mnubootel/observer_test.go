@@ -0,0 +1,212 @@
+package mnubootel
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+	"github.com/mnubo/smartobjects-go-client/mnubo/mnubotest"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTelemetryClient creates a client of srv observed by a recording tracer and meter.
+func newTelemetryClient(t *testing.T, srv *mnubotest.Server) (*mnubo.Mnubo, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+
+	m, err := srv.NewClient(
+		mnubo.WithCompression(mnubo.CompressionConfig{Request: true}),
+		mnubo.WithObservers(NewObserver(
+			sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+			sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		)),
+	)
+	if err != nil {
+		t.Fatalf("unable to create client: %+v", err)
+	}
+	return m, spans, reader
+}
+
+// eventsUnavailable returns a fault responding 503 to the next n requests sending events.
+func eventsUnavailable(n int) mnubotest.Fault {
+	f := mnubotest.ServiceUnavailable(n)
+	f.Path = "/api/v3/events"
+	return f
+}
+
+func spanAttribute(s sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestObserver_Traces(t *testing.T) {
+	srv := mnubotest.NewServer()
+	defer srv.Close()
+	m, spans, _ := newTelemetryClient(t, srv)
+	srv.Inject(eventsUnavailable(1))
+
+	events := make([]map[string]interface{}, 50)
+	for i := range events {
+		events[i] = map[string]interface{}{"x_object": map[string]string{"x_device_id": "device"}, "x_event_type": "type"}
+	}
+	var results []interface{}
+	err := m.Events.Send(events, mnubo.SendEventsOptions{}, &results)
+
+	if err != nil {
+		t.Fatalf("client call failed: %+v", err)
+	}
+
+	ended := spans.Ended()
+	names := make([]string, len(ended))
+	for i, s := range ended {
+		names[i] = s.Name()
+	}
+	expected := []string{"HTTP POST", "Mnubo.GetAccessToken", "HTTP POST", "HTTP POST", "Events.Send"}
+	if len(names) != len(expected) {
+		t.Fatalf("expecting spans: %+v, got: %+v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("%d, expecting: %s, got: %s", i, expected[i], names[i])
+		}
+	}
+
+	token, send := ended[1], ended[4]
+	if token.Parent().SpanID() != send.SpanContext().SpanID() {
+		t.Errorf("expecting token request to be a child of Events.Send")
+	}
+
+	cases := []struct {
+		Span       sdktrace.ReadOnlySpan
+		Parent     sdktrace.ReadOnlySpan
+		Attempt    int64
+		StatusCode int64
+		Status     codes.Code
+	}{
+		{ended[0], token, 1, http.StatusOK, codes.Unset},
+		{ended[2], send, 1, http.StatusServiceUnavailable, codes.Error},
+		{ended[3], send, 2, http.StatusOK, codes.Unset},
+	}
+	for i, c := range cases {
+		if c.Span.Parent().SpanID() != c.Parent.SpanContext().SpanID() {
+			t.Errorf("%d, expecting attempt to be a child of %s", i, c.Parent.Name())
+		}
+		if v := spanAttribute(c.Span, attributeAttempt).AsInt64(); v != c.Attempt {
+			t.Errorf("%d, expecting attempt: %d, got: %d", i, c.Attempt, v)
+		}
+		if v := spanAttribute(c.Span, attributeStatusCode).AsInt64(); v != c.StatusCode {
+			t.Errorf("%d, expecting status code: %d, got: %d", i, c.StatusCode, v)
+		}
+		if c.Span.Status().Code != c.Status {
+			t.Errorf("%d, expecting status: %s, got: %s", i, c.Status, c.Span.Status().Code)
+		}
+	}
+
+	if v := spanAttribute(send, attributeAttempts).AsInt64(); v != 2 {
+		t.Errorf("expecting 2 attempts, got: %d", v)
+	}
+	size, sent := spanAttribute(send, attributePayloadSize).AsInt64(), spanAttribute(send, attributeRequestSize).AsInt64()
+	if size == 0 || sent == 0 || sent >= size {
+		t.Errorf("expecting compressed payload to be smaller, got: %d and %d", size, sent)
+	}
+	if ratio := spanAttribute(send, attributeCompression).AsFloat64(); ratio <= 1 {
+		t.Errorf("expecting compression ratio > 1, got: %f", ratio)
+	}
+	if send.Status().Code != codes.Unset {
+		t.Errorf("expecting successful call, got: %+v", send.Status())
+	}
+}
+
+func TestObserver_Metrics(t *testing.T) {
+	srv := mnubotest.NewServer()
+	defer srv.Close()
+	m, _, reader := newTelemetryClient(t, srv)
+	srv.Inject(eventsUnavailable(1), mnubotest.Fault{Path: "/api/v3/events", RevokeTokens: true})
+
+	events := []map[string]interface{}{{"x_object": map[string]string{"x_device_id": "device"}, "x_event_type": "type"}}
+	var eventResults []interface{}
+	if err := m.Events.Send(events, mnubo.SendEventsOptions{}, &eventResults); err != nil {
+		t.Fatalf("client call failed: %+v", err)
+	}
+	var results mnubo.SearchResults
+	if err := m.Search.CreateBasicQuery(`{}`, &results); !mnubo.IsBadRequest(err) {
+		t.Fatalf("expecting bad request, got: %+v", err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("unable to collect metrics: %+v", err)
+	}
+	sums := map[string]int64{}
+	counts := map[string]uint64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, md := range sm.Metrics {
+			switch data := md.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					op, _ := dp.Attributes.Value(attributeOperation)
+					key := md.Name + " " + op.AsString()
+					if status, ok := dp.Attributes.Value(attributeStatusCode); ok {
+						key += " " + status.Emit()
+					}
+					sums[key] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					op, _ := dp.Attributes.Value(attributeOperation)
+					counts[md.Name+" "+op.AsString()] += dp.Count
+				}
+			}
+		}
+	}
+
+	cases := []struct {
+		Key      string
+		Expected int64
+	}{
+		{"mnubo.client.attempts Events.Send 503", 1},
+		{"mnubo.client.attempts Events.Send 401", 1},
+		{"mnubo.client.attempts Events.Send 200", 1},
+		{"mnubo.client.attempts Search.CreateBasicQuery 400", 1},
+		// The request replayed with a new token is not a retry.
+		{"mnubo.client.retries Events.Send", 1},
+		{"mnubo.client.retries Search.CreateBasicQuery", 0},
+		{"mnubo.client.operation.errors Events.Send", 0},
+		{"mnubo.client.operation.errors Search.CreateBasicQuery", 1},
+	}
+	for i, c := range cases {
+		if sums[c.Key] != c.Expected {
+			t.Errorf("%d, expecting %s: %d, got: %d", i, c.Key, c.Expected, sums[c.Key])
+		}
+	}
+	for _, op := range []string{"Events.Send", "Search.CreateBasicQuery"} {
+		if counts["mnubo.client.operation.duration "+op] != 1 {
+			t.Errorf("expecting one duration for %s, got: %+v", op, counts)
+		}
+	}
+}
+
+func TestObserver_Disabled(t *testing.T) {
+	srv := mnubotest.NewServer()
+	defer srv.Close()
+	m, err := srv.NewClient()
+	if err != nil {
+		t.Fatalf("unable to create client: %+v", err)
+	}
+
+	events := []map[string]interface{}{{"x_object": map[string]string{"x_device_id": "device"}, "x_event_type": "type"}}
+	var results []interface{}
+	if err := m.Events.Send(events, mnubo.SendEventsOptions{}, &results); err != nil {
+		t.Errorf("client call failed: %+v", err)
+	}
+}
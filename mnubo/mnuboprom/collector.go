@@ -0,0 +1,161 @@
+// Package mnuboprom exports the activity of SmartObjects clients as Prometheus metrics.
+//
+// It is a separate package so that clients not using Prometheus don't depend on it:
+//
+//	m, err := mnubo.New(
+//		mnubo.WithHost("YOUR_HOST_URL"),
+//		mnubo.WithClientCredentials("YOUR_CLIENT_ID", "YOUR_CLIENT_SECRET"),
+//		mnuboprom.WithCollector(prometheus.DefaultRegisterer),
+//	)
+package mnuboprom
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "mnubo_client"
+
+// Collector is a prometheus.Collector and a mnubo.Observer updated with the activity of the clients using it.
+// Register it once, it can be shared by several clients.
+type Collector struct {
+	requests       *prometheus.CounterVec
+	retries        *prometheus.CounterVec
+	tokenRefreshes *prometheus.CounterVec
+	payloadBytes   *prometheus.CounterVec
+	sentBytes      *prometheus.CounterVec
+	events         *prometheus.HistogramVec
+}
+
+// NewCollector creates the collector of the client metrics, prefixed by mnubo_client.
+func NewCollector() *Collector {
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "HTTP requests sent to SmartObjects, by endpoint, method and status code.",
+		}, []string{"endpoint", "method", "status"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "HTTP requests retried by the backoff loop, by endpoint.",
+		}, []string{"endpoint"}),
+		tokenRefreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_refreshes_total",
+			Help:      "Access tokens requested with the client id and secret, by result.",
+		}, []string{"result"}),
+		payloadBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "payload_bytes_total",
+			Help:      "Size of the request payloads before compression, by endpoint.",
+		}, []string{"endpoint"}),
+		sentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sent_bytes_total",
+			Help:      "Size of the request payloads after compression, by endpoint.",
+		}, []string{"endpoint"}),
+		events: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "events_per_send",
+			Help:      "Events ingested by each successful call sending events, by endpoint.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		}, []string{"endpoint"}),
+	}
+}
+
+// WithCollector registers a Collector with reg and notifies it of the client activity.
+// The Collector already registered with reg by another client is shared. The option fails
+// if the Collector cannot be registered.
+func WithCollector(reg prometheus.Registerer) mnubo.Option {
+	return func(m *mnubo.Mnubo) error {
+		c := NewCollector()
+		if err := reg.Register(c); err != nil {
+			var are prometheus.AlreadyRegisteredError
+			if !errors.As(err, &are) {
+				return err
+			}
+			existing, ok := are.ExistingCollector.(*Collector)
+			if !ok {
+				return err
+			}
+			c = existing
+		}
+		m.Observers = append(m.Observers, c)
+		return nil
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.retries.Describe(ch)
+	c.tokenRefreshes.Describe(ch)
+	c.payloadBytes.Describe(ch)
+	c.sentBytes.Describe(ch)
+	c.events.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.retries.Collect(ch)
+	c.tokenRefreshes.Collect(ch)
+	c.payloadBytes.Collect(ch)
+	c.sentBytes.Collect(ch)
+	c.events.Collect(ch)
+}
+
+// StartOperation implements mnubo.Observer.
+func (c *Collector) StartOperation(ctx context.Context, info mnubo.OperationInfo) (context.Context, mnubo.OperationObserver) {
+	return ctx, &operation{c: c, name: info.Name, events: info.Events}
+}
+
+// ObserveTokenRefresh implements mnubo.Observer.
+func (c *Collector) ObserveTokenRefresh(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	c.tokenRefreshes.WithLabelValues(result).Inc()
+}
+
+// operation updates the metrics of the endpoint of a client call.
+type operation struct {
+	c      *Collector
+	name   string
+	events int
+}
+
+// ObservePayload counts the bytes of a request payload, before and after compression.
+func (op *operation) ObservePayload(size int, sentSize int) {
+	op.c.payloadBytes.WithLabelValues(op.name).Add(float64(size))
+	op.c.sentBytes.WithLabelValues(op.name).Add(float64(sentSize))
+}
+
+// StartAttempt counts an HTTP request by status code, or as an error if no response was received,
+// and counts the retries.
+func (op *operation) StartAttempt(ctx context.Context, req *http.Request, attempt int) (context.Context, func(*http.Response, error)) {
+	if attempt > 1 {
+		op.c.retries.WithLabelValues(op.name).Inc()
+	}
+	return ctx, func(res *http.Response, err error) {
+		status := "error"
+		if res != nil {
+			status = strconv.Itoa(res.StatusCode)
+		}
+		op.c.requests.WithLabelValues(op.name, req.Method, status).Inc()
+	}
+}
+
+// End counts the events ingested by the call if it succeeded.
+func (op *operation) End(attempts int, err error) {
+	if err == nil && op.events > 0 {
+		op.c.events.WithLabelValues(op.name).Observe(float64(op.events))
+	}
+}
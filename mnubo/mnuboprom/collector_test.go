@@ -0,0 +1,105 @@
+package mnuboprom
+
+import (
+	"testing"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+	"github.com/mnubo/smartobjects-go-client/mnubo/mnubotest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestWithCollector(t *testing.T) {
+	srv := mnubotest.NewServer()
+	defer srv.Close()
+
+	registry := prometheus.NewRegistry()
+	m, err := srv.NewClient(mnubo.WithCompression(mnubo.CompressionConfig{Request: true}), WithCollector(registry))
+	if err != nil {
+		t.Fatalf("unable to create client: %+v", err)
+	}
+	c := m.Observers[0].(*Collector)
+
+	unavailable := mnubotest.ServiceUnavailable(1)
+	unavailable.Path = "/api/v3/events"
+	srv.Inject(unavailable, mnubotest.Fault{Path: "/api/v3/events", RevokeTokens: true})
+
+	events := make([]map[string]interface{}, 100)
+	for i := range events {
+		events[i] = map[string]interface{}{"x_object": map[string]string{"x_device_id": "device"}, "x_event_type": "type"}
+	}
+	var results []interface{}
+	if err := m.Events.Send(events, mnubo.SendEventsOptions{}, &results); err != nil {
+		t.Fatalf("client call failed: %+v", err)
+	}
+	var searchResults mnubo.SearchResults
+	if err := m.Search.CreateBasicQuery(`{}`, &searchResults); !mnubo.IsBadRequest(err) {
+		t.Fatalf("expecting bad request, got: %+v", err)
+	}
+
+	cases := []struct {
+		Name     string
+		Value    float64
+		Expected float64
+	}{
+		{"token refresh", testutil.ToFloat64(c.tokenRefreshes.WithLabelValues("success")), 2},
+		{"token request", testutil.ToFloat64(c.requests.WithLabelValues("Mnubo.GetAccessToken", "POST", "200")), 2},
+		{"events unavailable", testutil.ToFloat64(c.requests.WithLabelValues("Events.Send", "POST", "503")), 1},
+		{"events token rejected", testutil.ToFloat64(c.requests.WithLabelValues("Events.Send", "POST", "401")), 1},
+		{"events sent", testutil.ToFloat64(c.requests.WithLabelValues("Events.Send", "POST", "200")), 1},
+		{"bad query", testutil.ToFloat64(c.requests.WithLabelValues("Search.CreateBasicQuery", "POST", "400")), 1},
+		// The request replayed with a new token is not a retry.
+		{"events retries", testutil.ToFloat64(c.retries.WithLabelValues("Events.Send")), 1},
+	}
+	for i, c := range cases {
+		if c.Value != c.Expected {
+			t.Errorf("%d, expecting %s: %f, got: %f", i, c.Name, c.Expected, c.Value)
+		}
+	}
+
+	payload, sent := testutil.ToFloat64(c.payloadBytes.WithLabelValues("Events.Send")), testutil.ToFloat64(c.sentBytes.WithLabelValues("Events.Send"))
+	if payload == 0 || sent == 0 || sent >= payload {
+		t.Errorf("expecting gzip to reduce the payload size, got: %f and %f", payload, sent)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("unable to gather metrics: %+v", err)
+	}
+	found := false
+	for _, f := range families {
+		if f.GetName() != "mnubo_client_events_per_send" {
+			continue
+		}
+		found = true
+		h := f.GetMetric()[0].GetHistogram()
+		if h.GetSampleCount() != 1 || h.GetSampleSum() != 100 {
+			t.Errorf("expecting one call with 100 events, got: %+v", h)
+		}
+	}
+	if !found {
+		t.Errorf("expecting events_per_send to be gathered")
+	}
+
+	// Clients registering with the same registry share the collector.
+	other, err := srv.NewClient(WithCollector(registry))
+	if err != nil || other.Observers[0] != c {
+		t.Errorf("expecting the registered collector to be shared, got: %+v (%+v)", other.Observers, err)
+	}
+}
+
+func TestWithCollector_RegistrationError(t *testing.T) {
+	srv := mnubotest.NewServer()
+	defer srv.Close()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retries_total",
+		Help:      "Another metric with the same name.",
+	}))
+
+	if _, err := srv.NewClient(WithCollector(registry)); err == nil {
+		t.Errorf("expecting the registration error")
+	}
+}
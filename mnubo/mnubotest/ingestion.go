@@ -0,0 +1,162 @@
+package mnubotest
+
+import (
+	"context"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+// Events is a fake of mnubo.EventsAPI.
+type Events struct {
+	Recorder
+}
+
+// Send records the call and returns the response configured for "Send".
+func (f *Events) Send(events interface{}, options mnubo.SendEventsOptions, results interface{}) error {
+	return f.SendContext(context.Background(), events, options, results)
+}
+
+// SendContext records the call and returns the response configured for "Send".
+func (f *Events) SendContext(ctx context.Context, events interface{}, options mnubo.SendEventsOptions, results interface{}) error {
+	return f.call(ctx, "Send", results, events, options)
+}
+
+// SendFromDevice records the call and returns the response configured for "SendFromDevice".
+func (f *Events) SendFromDevice(deviceId string, events interface{}, options mnubo.SendEventsOptions, results interface{}) error {
+	return f.SendFromDeviceContext(context.Background(), deviceId, events, options, results)
+}
+
+// SendFromDeviceContext records the call and returns the response configured for "SendFromDevice".
+func (f *Events) SendFromDeviceContext(ctx context.Context, deviceId string, events interface{}, options mnubo.SendEventsOptions, results interface{}) error {
+	return f.call(ctx, "SendFromDevice", results, deviceId, events, options)
+}
+
+// Exists records the call and returns the response configured for "Exists".
+func (f *Events) Exists(eventIds []string, results *mnubo.EntitiesExist) error {
+	return f.ExistsContext(context.Background(), eventIds, results)
+}
+
+// ExistsContext records the call and returns the response configured for "Exists".
+func (f *Events) ExistsContext(ctx context.Context, eventIds []string, results *mnubo.EntitiesExist) error {
+	return f.call(ctx, "Exists", results, eventIds)
+}
+
+// Objects is a fake of mnubo.ObjectsAPI.
+type Objects struct {
+	Recorder
+}
+
+// Create records the call and returns the response configured for "Create".
+func (f *Objects) Create(objects interface{}, results interface{}) error {
+	return f.CreateContext(context.Background(), objects, results)
+}
+
+// CreateContext records the call and returns the response configured for "Create".
+func (f *Objects) CreateContext(ctx context.Context, objects interface{}, results interface{}) error {
+	return f.call(ctx, "Create", results, objects)
+}
+
+// Update records the call and returns the response configured for "Update".
+func (f *Objects) Update(objects interface{}, results interface{}) error {
+	return f.UpdateContext(context.Background(), objects, results)
+}
+
+// UpdateContext records the call and returns the response configured for "Update".
+func (f *Objects) UpdateContext(ctx context.Context, objects interface{}, results interface{}) error {
+	return f.call(ctx, "Update", results, objects)
+}
+
+// Delete records the call and returns the response configured for "Delete".
+func (f *Objects) Delete(deviceId string) error {
+	return f.DeleteContext(context.Background(), deviceId)
+}
+
+// DeleteContext records the call and returns the response configured for "Delete".
+func (f *Objects) DeleteContext(ctx context.Context, deviceId string) error {
+	return f.call(ctx, "Delete", nil, deviceId)
+}
+
+// Exist records the call and returns the response configured for "Exist".
+func (f *Objects) Exist(deviceIds []string, results *mnubo.EntitiesExist) error {
+	return f.ExistContext(context.Background(), deviceIds, results)
+}
+
+// ExistContext records the call and returns the response configured for "Exist".
+func (f *Objects) ExistContext(ctx context.Context, deviceIds []string, results *mnubo.EntitiesExist) error {
+	return f.call(ctx, "Exist", results, deviceIds)
+}
+
+// Owners is a fake of mnubo.OwnersAPI.
+type Owners struct {
+	Recorder
+}
+
+// Create records the call and returns the response configured for "Create".
+func (f *Owners) Create(owners interface{}, results interface{}) error {
+	return f.CreateContext(context.Background(), owners, results)
+}
+
+// CreateContext records the call and returns the response configured for "Create".
+func (f *Owners) CreateContext(ctx context.Context, owners interface{}, results interface{}) error {
+	return f.call(ctx, "Create", results, owners)
+}
+
+// Update records the call and returns the response configured for "Update".
+func (f *Owners) Update(owners interface{}, results interface{}) error {
+	return f.UpdateContext(context.Background(), owners, results)
+}
+
+// UpdateContext records the call and returns the response configured for "Update".
+func (f *Owners) UpdateContext(ctx context.Context, owners interface{}, results interface{}) error {
+	return f.call(ctx, "Update", results, owners)
+}
+
+// UpdateOwnerPassword records the call and returns the response configured for "UpdateOwnerPassword".
+func (f *Owners) UpdateOwnerPassword(username string, password string) error {
+	return f.UpdateOwnerPasswordContext(context.Background(), username, password)
+}
+
+// UpdateOwnerPasswordContext records the call and returns the response configured for "UpdateOwnerPassword".
+func (f *Owners) UpdateOwnerPasswordContext(ctx context.Context, username string, password string) error {
+	return f.call(ctx, "UpdateOwnerPassword", nil, username, password)
+}
+
+// Delete records the call and returns the response configured for "Delete".
+func (f *Owners) Delete(username string) error {
+	return f.DeleteContext(context.Background(), username)
+}
+
+// DeleteContext records the call and returns the response configured for "Delete".
+func (f *Owners) DeleteContext(ctx context.Context, username string) error {
+	return f.call(ctx, "Delete", nil, username)
+}
+
+// Exist records the call and returns the response configured for "Exist".
+func (f *Owners) Exist(usernames []string, results *mnubo.EntitiesExist) error {
+	return f.ExistContext(context.Background(), usernames, results)
+}
+
+// ExistContext records the call and returns the response configured for "Exist".
+func (f *Owners) ExistContext(ctx context.Context, usernames []string, results *mnubo.EntitiesExist) error {
+	return f.call(ctx, "Exist", results, usernames)
+}
+
+// Claim records the call and returns the response configured for "Claim".
+func (f *Owners) Claim(pairs []mnubo.ObjectOwnerPair, results *[]mnubo.ClaimResult) error {
+	return f.ClaimContext(context.Background(), pairs, results)
+}
+
+// ClaimContext records the call and returns the response configured for "Claim".
+func (f *Owners) ClaimContext(ctx context.Context, pairs []mnubo.ObjectOwnerPair, results *[]mnubo.ClaimResult) error {
+	return f.call(ctx, "Claim", results, pairs)
+}
+
+// Unclaim records the call and returns the response configured for "Unclaim".
+func (f *Owners) Unclaim(pairs []mnubo.ObjectOwnerPair, results *[]mnubo.ClaimResult) error {
+	return f.UnclaimContext(context.Background(), pairs, results)
+}
+
+// UnclaimContext records the call and returns the response configured for "Unclaim".
+func (f *Owners) UnclaimContext(ctx context.Context, pairs []mnubo.ObjectOwnerPair, results *[]mnubo.ClaimResult) error {
+	return f.call(ctx, "Unclaim", results, pairs)
+}
@@ -0,0 +1,352 @@
+package mnubotest
+
+import (
+	"context"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+// Model is a fake of mnubo.ModelAPI.
+type Model struct {
+	Recorder
+}
+
+// Export records the call and returns the response configured for "Export".
+func (f *Model) Export(results *mnubo.DataModel) error {
+	return f.ExportContext(context.Background(), results)
+}
+
+// ExportContext records the call and returns the response configured for "Export".
+func (f *Model) ExportContext(ctx context.Context, results *mnubo.DataModel) error {
+	return f.call(ctx, "Export", results)
+}
+
+// GetTimeseries records the call and returns the response configured for "GetTimeseries".
+func (f *Model) GetTimeseries(results *[]mnubo.Timeseries) error {
+	return f.GetTimeseriesContext(context.Background(), results)
+}
+
+// GetTimeseriesContext records the call and returns the response configured for "GetTimeseries".
+func (f *Model) GetTimeseriesContext(ctx context.Context, results *[]mnubo.Timeseries) error {
+	return f.call(ctx, "GetTimeseries", results)
+}
+
+// CreateObjectAttributes records the call and returns the response configured for "CreateObjectAttributes".
+func (f *Model) CreateObjectAttributes(oa []mnubo.ObjectAttribute) error {
+	return f.CreateObjectAttributesContext(context.Background(), oa)
+}
+
+// CreateObjectAttributesContext records the call and returns the response configured for "CreateObjectAttributes".
+func (f *Model) CreateObjectAttributesContext(ctx context.Context, oa []mnubo.ObjectAttribute) error {
+	return f.call(ctx, "CreateObjectAttributes", nil, oa)
+}
+
+// UpdateObjectAttribute records the call and returns the response configured for "UpdateObjectAttribute".
+func (f *Model) UpdateObjectAttribute(key string, oa mnubo.ObjectAttribute) error {
+	return f.UpdateObjectAttributeContext(context.Background(), key, oa)
+}
+
+// UpdateObjectAttributeContext records the call and returns the response configured for "UpdateObjectAttribute".
+func (f *Model) UpdateObjectAttributeContext(ctx context.Context, key string, oa mnubo.ObjectAttribute) error {
+	return f.call(ctx, "UpdateObjectAttribute", nil, key, oa)
+}
+
+// GenerateObjectAttributeDeployCode records the call and returns the response configured for "GenerateObjectAttributeDeployCode".
+func (f *Model) GenerateObjectAttributeDeployCode(key string, results *mnubo.ChallengeCode) error {
+	return f.GenerateObjectAttributeDeployCodeContext(context.Background(), key, results)
+}
+
+// GenerateObjectAttributeDeployCodeContext records the call and returns the response configured for "GenerateObjectAttributeDeployCode".
+func (f *Model) GenerateObjectAttributeDeployCodeContext(ctx context.Context, key string, results *mnubo.ChallengeCode) error {
+	return f.call(ctx, "GenerateObjectAttributeDeployCode", results, key)
+}
+
+// ApplyObjectAttributeDeployCode records the call and returns the response configured for "ApplyObjectAttributeDeployCode".
+func (f *Model) ApplyObjectAttributeDeployCode(key string, cc mnubo.ChallengeCode) error {
+	return f.ApplyObjectAttributeDeployCodeContext(context.Background(), key, cc)
+}
+
+// ApplyObjectAttributeDeployCodeContext records the call and returns the response configured for "ApplyObjectAttributeDeployCode".
+func (f *Model) ApplyObjectAttributeDeployCodeContext(ctx context.Context, key string, cc mnubo.ChallengeCode) error {
+	return f.call(ctx, "ApplyObjectAttributeDeployCode", nil, key, cc)
+}
+
+// DeployObjectAttributeToProduction records the call and returns the response configured for "DeployObjectAttributeToProduction".
+func (f *Model) DeployObjectAttributeToProduction(key string) error {
+	return f.DeployObjectAttributeToProductionContext(context.Background(), key)
+}
+
+// DeployObjectAttributeToProductionContext records the call and returns the response configured for "DeployObjectAttributeToProduction".
+func (f *Model) DeployObjectAttributeToProductionContext(ctx context.Context, key string) error {
+	return f.call(ctx, "DeployObjectAttributeToProduction", nil, key)
+}
+
+// GetObjectAttributes records the call and returns the response configured for "GetObjectAttributes".
+func (f *Model) GetObjectAttributes(results *[]mnubo.ObjectAttribute) error {
+	return f.GetObjectAttributesContext(context.Background(), results)
+}
+
+// GetObjectAttributesContext records the call and returns the response configured for "GetObjectAttributes".
+func (f *Model) GetObjectAttributesContext(ctx context.Context, results *[]mnubo.ObjectAttribute) error {
+	return f.call(ctx, "GetObjectAttributes", results)
+}
+
+// CreateTimeseries records the call and returns the response configured for "CreateTimeseries".
+func (f *Model) CreateTimeseries(ts []mnubo.Timeseries) error {
+	return f.CreateTimeseriesContext(context.Background(), ts)
+}
+
+// CreateTimeseriesContext records the call and returns the response configured for "CreateTimeseries".
+func (f *Model) CreateTimeseriesContext(ctx context.Context, ts []mnubo.Timeseries) error {
+	return f.call(ctx, "CreateTimeseries", nil, ts)
+}
+
+// UpdateTimeseries records the call and returns the response configured for "UpdateTimeseries".
+func (f *Model) UpdateTimeseries(key string, ts mnubo.Timeseries) error {
+	return f.UpdateTimeseriesContext(context.Background(), key, ts)
+}
+
+// UpdateTimeseriesContext records the call and returns the response configured for "UpdateTimeseries".
+func (f *Model) UpdateTimeseriesContext(ctx context.Context, key string, ts mnubo.Timeseries) error {
+	return f.call(ctx, "UpdateTimeseries", nil, key, ts)
+}
+
+// GenerateTimeseriesDeployCode records the call and returns the response configured for "GenerateTimeseriesDeployCode".
+func (f *Model) GenerateTimeseriesDeployCode(key string, results *mnubo.ChallengeCode) error {
+	return f.GenerateTimeseriesDeployCodeContext(context.Background(), key, results)
+}
+
+// GenerateTimeseriesDeployCodeContext records the call and returns the response configured for "GenerateTimeseriesDeployCode".
+func (f *Model) GenerateTimeseriesDeployCodeContext(ctx context.Context, key string, results *mnubo.ChallengeCode) error {
+	return f.call(ctx, "GenerateTimeseriesDeployCode", results, key)
+}
+
+// ApplyTimeseriesDeployCode records the call and returns the response configured for "ApplyTimeseriesDeployCode".
+func (f *Model) ApplyTimeseriesDeployCode(key string, cc mnubo.ChallengeCode) error {
+	return f.ApplyTimeseriesDeployCodeContext(context.Background(), key, cc)
+}
+
+// ApplyTimeseriesDeployCodeContext records the call and returns the response configured for "ApplyTimeseriesDeployCode".
+func (f *Model) ApplyTimeseriesDeployCodeContext(ctx context.Context, key string, cc mnubo.ChallengeCode) error {
+	return f.call(ctx, "ApplyTimeseriesDeployCode", nil, key, cc)
+}
+
+// DeployTimeseriesToProduction records the call and returns the response configured for "DeployTimeseriesToProduction".
+func (f *Model) DeployTimeseriesToProduction(key string) error {
+	return f.DeployTimeseriesToProductionContext(context.Background(), key)
+}
+
+// DeployTimeseriesToProductionContext records the call and returns the response configured for "DeployTimeseriesToProduction".
+func (f *Model) DeployTimeseriesToProductionContext(ctx context.Context, key string) error {
+	return f.call(ctx, "DeployTimeseriesToProduction", nil, key)
+}
+
+// CreateOwnerAttributes records the call and returns the response configured for "CreateOwnerAttributes".
+func (f *Model) CreateOwnerAttributes(oa []mnubo.OwnerAttribute) error {
+	return f.CreateOwnerAttributesContext(context.Background(), oa)
+}
+
+// CreateOwnerAttributesContext records the call and returns the response configured for "CreateOwnerAttributes".
+func (f *Model) CreateOwnerAttributesContext(ctx context.Context, oa []mnubo.OwnerAttribute) error {
+	return f.call(ctx, "CreateOwnerAttributes", nil, oa)
+}
+
+// UpdateOwnerAttribute records the call and returns the response configured for "UpdateOwnerAttribute".
+func (f *Model) UpdateOwnerAttribute(key string, oa mnubo.OwnerAttribute) error {
+	return f.UpdateOwnerAttributeContext(context.Background(), key, oa)
+}
+
+// UpdateOwnerAttributeContext records the call and returns the response configured for "UpdateOwnerAttribute".
+func (f *Model) UpdateOwnerAttributeContext(ctx context.Context, key string, oa mnubo.OwnerAttribute) error {
+	return f.call(ctx, "UpdateOwnerAttribute", nil, key, oa)
+}
+
+// GenerateOwnerAttributeDeployCode records the call and returns the response configured for "GenerateOwnerAttributeDeployCode".
+func (f *Model) GenerateOwnerAttributeDeployCode(key string, results *mnubo.ChallengeCode) error {
+	return f.GenerateOwnerAttributeDeployCodeContext(context.Background(), key, results)
+}
+
+// GenerateOwnerAttributeDeployCodeContext records the call and returns the response configured for "GenerateOwnerAttributeDeployCode".
+func (f *Model) GenerateOwnerAttributeDeployCodeContext(ctx context.Context, key string, results *mnubo.ChallengeCode) error {
+	return f.call(ctx, "GenerateOwnerAttributeDeployCode", results, key)
+}
+
+// ApplyOwnerAttributeDeployCode records the call and returns the response configured for "ApplyOwnerAttributeDeployCode".
+func (f *Model) ApplyOwnerAttributeDeployCode(key string, cc mnubo.ChallengeCode) error {
+	return f.ApplyOwnerAttributeDeployCodeContext(context.Background(), key, cc)
+}
+
+// ApplyOwnerAttributeDeployCodeContext records the call and returns the response configured for "ApplyOwnerAttributeDeployCode".
+func (f *Model) ApplyOwnerAttributeDeployCodeContext(ctx context.Context, key string, cc mnubo.ChallengeCode) error {
+	return f.call(ctx, "ApplyOwnerAttributeDeployCode", nil, key, cc)
+}
+
+// DeployOwnerAttributeToProduction records the call and returns the response configured for "DeployOwnerAttributeToProduction".
+func (f *Model) DeployOwnerAttributeToProduction(key string) error {
+	return f.DeployOwnerAttributeToProductionContext(context.Background(), key)
+}
+
+// DeployOwnerAttributeToProductionContext records the call and returns the response configured for "DeployOwnerAttributeToProduction".
+func (f *Model) DeployOwnerAttributeToProductionContext(ctx context.Context, key string) error {
+	return f.call(ctx, "DeployOwnerAttributeToProduction", nil, key)
+}
+
+// GetOwnerAttributes records the call and returns the response configured for "GetOwnerAttributes".
+func (f *Model) GetOwnerAttributes(results *[]mnubo.OwnerAttribute) error {
+	return f.GetOwnerAttributesContext(context.Background(), results)
+}
+
+// GetOwnerAttributesContext records the call and returns the response configured for "GetOwnerAttributes".
+func (f *Model) GetOwnerAttributesContext(ctx context.Context, results *[]mnubo.OwnerAttribute) error {
+	return f.call(ctx, "GetOwnerAttributes", results)
+}
+
+// GetEventTypes records the call and returns the response configured for "GetEventTypes".
+func (f *Model) GetEventTypes(results *[]mnubo.EventType) error {
+	return f.GetEventTypesContext(context.Background(), results)
+}
+
+// GetEventTypesContext records the call and returns the response configured for "GetEventTypes".
+func (f *Model) GetEventTypesContext(ctx context.Context, results *[]mnubo.EventType) error {
+	return f.call(ctx, "GetEventTypes", results)
+}
+
+// CreateEventTypes records the call and returns the response configured for "CreateEventTypes".
+func (f *Model) CreateEventTypes(et []mnubo.EventType) error {
+	return f.CreateEventTypesContext(context.Background(), et)
+}
+
+// CreateEventTypesContext records the call and returns the response configured for "CreateEventTypes".
+func (f *Model) CreateEventTypesContext(ctx context.Context, et []mnubo.EventType) error {
+	return f.call(ctx, "CreateEventTypes", nil, et)
+}
+
+// UpdateEventType records the call and returns the response configured for "UpdateEventType".
+func (f *Model) UpdateEventType(key string, et mnubo.EventType) error {
+	return f.UpdateEventTypeContext(context.Background(), key, et)
+}
+
+// UpdateEventTypeContext records the call and returns the response configured for "UpdateEventType".
+func (f *Model) UpdateEventTypeContext(ctx context.Context, key string, et mnubo.EventType) error {
+	return f.call(ctx, "UpdateEventType", nil, key, et)
+}
+
+// DeleteEventType records the call and returns the response configured for "DeleteEventType".
+func (f *Model) DeleteEventType(key string) error {
+	return f.DeleteEventTypeContext(context.Background(), key)
+}
+
+// DeleteEventTypeContext records the call and returns the response configured for "DeleteEventType".
+func (f *Model) DeleteEventTypeContext(ctx context.Context, key string) error {
+	return f.call(ctx, "DeleteEventType", nil, key)
+}
+
+// AddEventTypeRelation records the call and returns the response configured for "AddEventTypeRelation".
+func (f *Model) AddEventTypeRelation(typeKey string, entityKey string) error {
+	return f.AddEventTypeRelationContext(context.Background(), typeKey, entityKey)
+}
+
+// AddEventTypeRelationContext records the call and returns the response configured for "AddEventTypeRelation".
+func (f *Model) AddEventTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error {
+	return f.call(ctx, "AddEventTypeRelation", nil, typeKey, entityKey)
+}
+
+// RemoveEventTypeRelation records the call and returns the response configured for "RemoveEventTypeRelation".
+func (f *Model) RemoveEventTypeRelation(typeKey string, entityKey string) error {
+	return f.RemoveEventTypeRelationContext(context.Background(), typeKey, entityKey)
+}
+
+// RemoveEventTypeRelationContext records the call and returns the response configured for "RemoveEventTypeRelation".
+func (f *Model) RemoveEventTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error {
+	return f.call(ctx, "RemoveEventTypeRelation", nil, typeKey, entityKey)
+}
+
+// GetObjectTypes records the call and returns the response configured for "GetObjectTypes".
+func (f *Model) GetObjectTypes(results *[]mnubo.ObjectType) error {
+	return f.GetObjectTypesContext(context.Background(), results)
+}
+
+// GetObjectTypesContext records the call and returns the response configured for "GetObjectTypes".
+func (f *Model) GetObjectTypesContext(ctx context.Context, results *[]mnubo.ObjectType) error {
+	return f.call(ctx, "GetObjectTypes", results)
+}
+
+// CreateObjectTypes records the call and returns the response configured for "CreateObjectTypes".
+func (f *Model) CreateObjectTypes(ot []mnubo.ObjectType) error {
+	return f.CreateObjectTypesContext(context.Background(), ot)
+}
+
+// CreateObjectTypesContext records the call and returns the response configured for "CreateObjectTypes".
+func (f *Model) CreateObjectTypesContext(ctx context.Context, ot []mnubo.ObjectType) error {
+	return f.call(ctx, "CreateObjectTypes", nil, ot)
+}
+
+// UpdateObjectType records the call and returns the response configured for "UpdateObjectType".
+func (f *Model) UpdateObjectType(key string, ot mnubo.ObjectType) error {
+	return f.UpdateObjectTypeContext(context.Background(), key, ot)
+}
+
+// UpdateObjectTypeContext records the call and returns the response configured for "UpdateObjectType".
+func (f *Model) UpdateObjectTypeContext(ctx context.Context, key string, ot mnubo.ObjectType) error {
+	return f.call(ctx, "UpdateObjectType", nil, key, ot)
+}
+
+// DeleteObjectType records the call and returns the response configured for "DeleteObjectType".
+func (f *Model) DeleteObjectType(key string) error {
+	return f.DeleteObjectTypeContext(context.Background(), key)
+}
+
+// DeleteObjectTypeContext records the call and returns the response configured for "DeleteObjectType".
+func (f *Model) DeleteObjectTypeContext(ctx context.Context, key string) error {
+	return f.call(ctx, "DeleteObjectType", nil, key)
+}
+
+// AddObjectTypeRelation records the call and returns the response configured for "AddObjectTypeRelation".
+func (f *Model) AddObjectTypeRelation(typeKey string, entityKey string) error {
+	return f.AddObjectTypeRelationContext(context.Background(), typeKey, entityKey)
+}
+
+// AddObjectTypeRelationContext records the call and returns the response configured for "AddObjectTypeRelation".
+func (f *Model) AddObjectTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error {
+	return f.call(ctx, "AddObjectTypeRelation", nil, typeKey, entityKey)
+}
+
+// RemoveObjectTypeRelation records the call and returns the response configured for "RemoveObjectTypeRelation".
+func (f *Model) RemoveObjectTypeRelation(typeKey string, entityKey string) error {
+	return f.RemoveObjectTypeRelationContext(context.Background(), typeKey, entityKey)
+}
+
+// RemoveObjectTypeRelationContext records the call and returns the response configured for "RemoveObjectTypeRelation".
+func (f *Model) RemoveObjectTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error {
+	return f.call(ctx, "RemoveObjectTypeRelation", nil, typeKey, entityKey)
+}
+
+// GenerateResetCode records the call and returns the response configured for "GenerateResetCode".
+func (f *Model) GenerateResetCode(results *mnubo.ChallengeCode) error {
+	return f.GenerateResetCodeContext(context.Background(), results)
+}
+
+// GenerateResetCodeContext records the call and returns the response configured for "GenerateResetCode".
+func (f *Model) GenerateResetCodeContext(ctx context.Context, results *mnubo.ChallengeCode) error {
+	return f.call(ctx, "GenerateResetCode", results)
+}
+
+// ApplyResetCode records the call and returns the response configured for "ApplyResetCode".
+func (f *Model) ApplyResetCode(cc mnubo.ChallengeCode) error {
+	return f.ApplyResetCodeContext(context.Background(), cc)
+}
+
+// ApplyResetCodeContext records the call and returns the response configured for "ApplyResetCode".
+func (f *Model) ApplyResetCodeContext(ctx context.Context, cc mnubo.ChallengeCode) error {
+	return f.call(ctx, "ApplyResetCode", nil, cc)
+}
+
+// ResetDataModel records the call and returns the response configured for "ResetDataModel".
+func (f *Model) ResetDataModel() error {
+	return f.ResetDataModelContext(context.Background())
+}
+
+// ResetDataModelContext records the call and returns the response configured for "ResetDataModel".
+func (f *Model) ResetDataModelContext(ctx context.Context) error {
+	return f.call(ctx, "ResetDataModel", nil)
+}
@@ -0,0 +1,148 @@
+// Package mnubotest provides fakes of the SmartObjects client helpers, to test code depending on
+// mnubo.EventsAPI, mnubo.ObjectsAPI, mnubo.OwnersAPI, mnubo.SearchAPI and mnubo.ModelAPI without a platform.
+package mnubotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+// Call is a call received by a fake.
+type Call struct {
+	// Method is the name of the called function without its Context suffix, like "Send" for SendContext.
+	Method string
+	// Args are the arguments of the call, except the context and the results.
+	Args []interface{}
+}
+
+// Response is returned by a fake function.
+type Response struct {
+	// Results are copied into the results of the call through JSON, like a platform response.
+	// The results of search queries are given as mnubo.SearchResults, including for mnubo.SearchRows.
+	Results interface{}
+	// Err is returned by the call.
+	Err error
+}
+
+// Recorder records the calls of a fake and returns their configured responses.
+// It is embedded by the fakes, and is safe for concurrent use.
+type Recorder struct {
+	mu        sync.Mutex
+	calls     []Call
+	responses map[string][]Response
+}
+
+// On configures the responses of method, returned in order to its calls. The last one is returned
+// once the others have been, and calls of methods without responses succeed without results.
+func (r *Recorder) On(method string, responses ...Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.responses == nil {
+		r.responses = make(map[string][]Response)
+	}
+	r.responses[method] = responses
+}
+
+// Calls returns the recorded calls, in order.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Call(nil), r.calls...)
+}
+
+// CallsTo returns the recorded calls of method, in order.
+func (r *Recorder) CallsTo(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var calls []Call
+	for _, c := range r.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Reset forgets the recorded calls and the configured responses.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = nil
+	r.responses = nil
+}
+
+// call records a call of method and sets results according to its next response.
+// Calls fail with the error of ctx when it is done, like the client.
+func (r *Recorder) call(ctx context.Context, method string, results interface{}, args ...interface{}) error {
+	res := r.record(method, args)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+	return setResults(res.Results, results)
+}
+
+// record appends a call of method and returns its next response.
+func (r *Recorder) record(method string, args []interface{}) Response {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+	responses := r.responses[method]
+	if len(responses) == 0 {
+		return Response{}
+	}
+	if len(responses) > 1 {
+		r.responses[method] = responses[1:]
+	}
+	return responses[0]
+}
+
+// setResults copies value into results through JSON. Rows are given to the OnRow function of mnubo.SearchRows.
+func setResults(value interface{}, results interface{}) error {
+	if value == nil || results == nil {
+		return nil
+	}
+	if v := reflect.ValueOf(results); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	rows, ok := results.(*mnubo.SearchRows)
+	if !ok {
+		return json.Unmarshal(bytes, results)
+	}
+
+	var sr mnubo.SearchResults
+	if err := json.Unmarshal(bytes, &sr); err != nil {
+		return err
+	}
+	rows.Columns = sr.Columns
+	for _, row := range sr.Rows {
+		if rows.OnRow == nil {
+			break
+		}
+		if err := rows.OnRow(row); err != nil {
+			if errors.Is(err, mnubo.ErrStopRows) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package mnubotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+var (
+	_ mnubo.EventsAPI  = (*mnubo.Events)(nil)
+	_ mnubo.ObjectsAPI = (*mnubo.Objects)(nil)
+	_ mnubo.OwnersAPI  = (*mnubo.Owners)(nil)
+	_ mnubo.SearchAPI  = (*mnubo.Search)(nil)
+	_ mnubo.ModelAPI   = (*mnubo.Model)(nil)
+
+	_ mnubo.EventsAPI  = (*Events)(nil)
+	_ mnubo.ObjectsAPI = (*Objects)(nil)
+	_ mnubo.OwnersAPI  = (*Owners)(nil)
+	_ mnubo.SearchAPI  = (*Search)(nil)
+	_ mnubo.ModelAPI   = (*Model)(nil)
+)
+
+type event struct {
+	XEventType string `json:"x_event_type"`
+}
+
+func TestEvents(t *testing.T) {
+	failure := errors.New("failure")
+	f := &Events{}
+	f.On("Send",
+		Response{Err: failure},
+		Response{Results: []mnubo.SendEventsReport{{ID: "event-1", Result: "success"}}},
+	)
+
+	events := []event{{XEventType: "speed"}}
+	options := mnubo.SendEventsOptions{ReportResults: true}
+	if _, err := mnubo.SendEvents(context.Background(), f, events, options); !errors.Is(err, failure) {
+		t.Errorf("expecting the first response to fail, got: %+v", err)
+	}
+	for i := 0; i < 2; i++ {
+		reports, err := mnubo.SendEvents(context.Background(), f, events, options)
+		if err != nil || len(reports) != 1 || reports[0].ID != "event-1" {
+			t.Errorf("%d, expecting the last response, got: %+v (%+v)", i, reports, err)
+		}
+	}
+
+	var exist mnubo.EntitiesExist
+	if err := f.Exists([]string{"event-1"}, &exist); err != nil || exist != nil {
+		t.Errorf("expecting no results without response, got: %+v (%+v)", exist, err)
+	}
+
+	calls := f.CallsTo("Send")
+	if len(calls) != 3 || len(f.Calls()) != 4 {
+		t.Fatalf("expecting 3 calls to Send out of 4, got: %+v", f.Calls())
+	}
+	if sent := calls[0].Args[0].([]event); sent[0].XEventType != "speed" || calls[0].Args[1] != options {
+		t.Errorf("unexpected arguments: %+v", calls[0].Args)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := f.SendContext(ctx, events, options, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expecting the context error, got: %+v", err)
+	}
+
+	f.Reset()
+	if len(f.Calls()) != 0 {
+		t.Errorf("expecting the calls to be reset, got: %+v", f.Calls())
+	}
+}
+
+func TestSearch(t *testing.T) {
+	f := &Search{}
+	results := mnubo.SearchResults{
+		Columns: []mnubo.SearchResultsColumn{{Label: "x_device_id", Type: "TEXT"}, {Label: "count", Type: "LONG"}},
+		Rows:    [][]interface{}{{"device-1", 1}, {"device-2", 2}},
+	}
+	f.On("CreateBasicQuery", Response{Results: results})
+	f.On("CreateBasicQueryWithString", Response{Results: results})
+	f.On("Rows", Response{Results: results})
+
+	type count struct {
+		XDeviceID string `json:"x_device_id"`
+		Count     int    `json:"count"`
+	}
+	counts, err := mnubo.Query[count](context.Background(), f, map[string]string{"from": "event"})
+	if err != nil || len(counts) != 2 || counts[1] != (count{"device-2", 2}) {
+		t.Errorf("unexpected query results: %+v (%+v)", counts, err)
+	}
+
+	var sr mnubo.SearchResults
+	if err := f.CreateBasicQueryWithString(`{"from":"event"}`, &sr); err != nil || len(sr.Rows) != 2 {
+		t.Errorf("unexpected search results: %+v (%+v)", sr, err)
+	}
+
+	var devices []interface{}
+	for row, err := range f.Rows(context.Background(), map[string]string{"from": "event"}) {
+		if err != nil {
+			t.Fatalf("iteration failed: %+v", err)
+		}
+		devices = append(devices, row[0])
+		break
+	}
+	if len(devices) != 1 || devices[0] != "device-1" {
+		t.Errorf("expecting iteration to stop after the first row, got: %+v", devices)
+	}
+
+	if len(f.CallsTo("CreateBasicQuery")) != 1 || len(f.CallsTo("CreateBasicQueryWithString")) != 1 || len(f.CallsTo("Rows")) != 1 {
+		t.Errorf("unexpected calls: %+v", f.Calls())
+	}
+}
+
+func TestModel(t *testing.T) {
+	f := &Model{}
+	f.On("GenerateResetCode", Response{Results: mnubo.ChallengeCode{Code: "code-1"}})
+
+	var cc mnubo.ChallengeCode
+	if err := f.GenerateResetCode(&cc); err != nil || cc.Code != "code-1" {
+		t.Errorf("unexpected challenge code: %+v (%+v)", cc, err)
+	}
+	if err := f.ApplyResetCode(cc); err != nil {
+		t.Errorf("expecting the call to succeed: %+v", err)
+	}
+	calls := f.Calls()
+	if len(calls) != 2 || calls[1].Method != "ApplyResetCode" || calls[1].Args[0] != cc {
+		t.Errorf("unexpected calls: %+v", calls)
+	}
+}
@@ -0,0 +1,101 @@
+package mnubotest
+
+import (
+	"context"
+	"iter"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+// Search is a fake of mnubo.SearchAPI.
+type Search struct {
+	Recorder
+}
+
+// CreateBasicQuery records the call and returns the response configured for "CreateBasicQuery".
+func (f *Search) CreateBasicQuery(mql interface{}, results interface{}) error {
+	return f.CreateBasicQueryContext(context.Background(), mql, results)
+}
+
+// CreateBasicQueryContext records the call and returns the response configured for "CreateBasicQuery".
+func (f *Search) CreateBasicQueryContext(ctx context.Context, mql interface{}, results interface{}) error {
+	return f.call(ctx, "CreateBasicQuery", results, mql)
+}
+
+// CreateBasicQueryWithString records the call and returns the response configured for "CreateBasicQueryWithString".
+func (f *Search) CreateBasicQueryWithString(mql string, results interface{}) error {
+	return f.CreateBasicQueryWithStringContext(context.Background(), mql, results)
+}
+
+// CreateBasicQueryWithStringContext records the call and returns the response configured for "CreateBasicQueryWithString".
+func (f *Search) CreateBasicQueryWithStringContext(ctx context.Context, mql string, results interface{}) error {
+	return f.call(ctx, "CreateBasicQueryWithString", results, mql)
+}
+
+// CreateBasicQueryWithBytes records the call and returns the response configured for "CreateBasicQueryWithBytes".
+func (f *Search) CreateBasicQueryWithBytes(mql []byte, results interface{}) error {
+	return f.CreateBasicQueryWithBytesContext(context.Background(), mql, results)
+}
+
+// CreateBasicQueryWithBytesContext records the call and returns the response configured for "CreateBasicQueryWithBytes".
+func (f *Search) CreateBasicQueryWithBytesContext(ctx context.Context, mql []byte, results interface{}) error {
+	return f.call(ctx, "CreateBasicQueryWithBytes", results, mql)
+}
+
+// ValidateQuery records the call and returns the response configured for "ValidateQuery".
+func (f *Search) ValidateQuery(mql interface{}, results *mnubo.QueryValidation) error {
+	return f.ValidateQueryContext(context.Background(), mql, results)
+}
+
+// ValidateQueryContext records the call and returns the response configured for "ValidateQuery".
+func (f *Search) ValidateQueryContext(ctx context.Context, mql interface{}, results *mnubo.QueryValidation) error {
+	return f.call(ctx, "ValidateQuery", results, mql)
+}
+
+// ValidateQueryWithString records the call and returns the response configured for "ValidateQueryWithString".
+func (f *Search) ValidateQueryWithString(mql string, results *mnubo.QueryValidation) error {
+	return f.ValidateQueryWithStringContext(context.Background(), mql, results)
+}
+
+// ValidateQueryWithStringContext records the call and returns the response configured for "ValidateQueryWithString".
+func (f *Search) ValidateQueryWithStringContext(ctx context.Context, mql string, results *mnubo.QueryValidation) error {
+	return f.call(ctx, "ValidateQueryWithString", results, mql)
+}
+
+// ValidateQueryWithBytes records the call and returns the response configured for "ValidateQueryWithBytes".
+func (f *Search) ValidateQueryWithBytes(mql []byte, results *mnubo.QueryValidation) error {
+	return f.ValidateQueryWithBytesContext(context.Background(), mql, results)
+}
+
+// ValidateQueryWithBytesContext records the call and returns the response configured for "ValidateQueryWithBytes".
+func (f *Search) ValidateQueryWithBytesContext(ctx context.Context, mql []byte, results *mnubo.QueryValidation) error {
+	return f.call(ctx, "ValidateQueryWithBytes", results, mql)
+}
+
+// GetDatasets records the call and returns the response configured for "GetDatasets".
+func (f *Search) GetDatasets(results *[]mnubo.Dataset) error {
+	return f.GetDatasetsContext(context.Background(), results)
+}
+
+// GetDatasetsContext records the call and returns the response configured for "GetDatasets".
+func (f *Search) GetDatasetsContext(ctx context.Context, results *[]mnubo.Dataset) error {
+	return f.call(ctx, "GetDatasets", results)
+}
+
+// Rows returns an iterator over the rows of the response configured for "Rows", the call is recorded when iterating.
+// The results of the response are given as mnubo.SearchResults, or any value with the same JSON encoding.
+func (f *Search) Rows(ctx context.Context, mql interface{}) iter.Seq2[[]interface{}, error] {
+	return func(yield func([]interface{}, error) bool) {
+		rows := &mnubo.SearchRows{
+			OnRow: func(row []interface{}) error {
+				if !yield(row, nil) {
+					return mnubo.ErrStopRows
+				}
+				return nil
+			},
+		}
+		if err := f.call(ctx, "Rows", rows, mql); err != nil {
+			yield(nil, err)
+		}
+	}
+}
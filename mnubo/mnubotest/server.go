@@ -0,0 +1,313 @@
+package mnubotest
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+const (
+	// DefaultClientID and DefaultClientSecret are the credentials accepted by a new Server.
+	DefaultClientID     = "mnubotest-client-id"
+	DefaultClientSecret = "mnubotest-client-secret"
+
+	// TokenExpiresIn is the lifetime of the access tokens issued by a Server, in milliseconds.
+	TokenExpiresIn = 3600 * 1000
+)
+
+// Server is an in-memory SmartObjects platform serving the endpoints of the client over HTTP, to test
+// the SDK and the code using it without a platform. It stores the events, objects, owners and data model
+// it receives, and answers basic search queries over them.
+// Platform failures can be injected with Inject.
+// The server is started by NewServer and must be closed with Close.
+type Server struct {
+	*httptest.Server
+	// ClientID and ClientSecret are the credentials accepted by /oauth/token.
+	ClientID     string
+	ClientSecret string
+
+	mu         sync.Mutex
+	tokens     map[string]bool
+	events     []map[string]interface{}
+	eventIds   map[string]bool
+	objects    map[string]map[string]interface{}
+	owners     map[string]map[string]interface{}
+	model      *model
+	challenges map[string]string
+	faults     []*Fault
+	requests   []string
+}
+
+// NewServer starts a new Server accepting DefaultClientID and DefaultClientSecret.
+func NewServer() *Server {
+	s := &Server{
+		ClientID:     DefaultClientID,
+		ClientSecret: DefaultClientSecret,
+		tokens:       make(map[string]bool),
+		eventIds:     make(map[string]bool),
+		objects:      make(map[string]map[string]interface{}),
+		owners:       make(map[string]map[string]interface{}),
+		challenges:   make(map[string]string),
+		model:        newModel(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth/token", s.handleToken)
+	s.handleIngestion(mux)
+	s.handleModel(mux)
+	s.handleSearch(mux)
+	s.Server = httptest.NewServer(s.injectFaults(s.authenticate(mux)))
+	return s
+}
+
+// NewClient creates a client sending its requests to the server with its credentials, configured by opts.
+func (s *Server) NewClient(opts ...mnubo.Option) (*mnubo.Mnubo, error) {
+	return mnubo.New(append([]mnubo.Option{
+		mnubo.WithHost(s.URL),
+		mnubo.WithClientCredentials(s.ClientID, s.ClientSecret),
+	}, opts...)...)
+}
+
+// IssueToken returns a new access token accepted by the server, to create clients with static tokens.
+func (s *Server) IssueToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.issueToken()
+}
+
+// RevokeTokens revokes every access token issued by the server, the following requests using them are rejected.
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens = make(map[string]bool)
+}
+
+// Reset removes the stored events, objects, owners and data model. Faults are kept, see ClearFaults.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reset()
+}
+
+// reset removes the stored data with the mutex held.
+// Maps are cleared rather than replaced, as the handlers select them before locking the mutex.
+func (s *Server) reset() {
+	s.events = nil
+	clear(s.eventIds)
+	clear(s.objects)
+	clear(s.owners)
+	clear(s.challenges)
+	s.model = newModel()
+}
+
+// Events returns the stored events, in the order they were received.
+// The device id of the event object is given as x_device_id.
+func (s *Server) Events() []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]map[string]interface{}, len(s.events))
+	for i, e := range s.events {
+		events[i] = copyEntity(e)
+	}
+	return events
+}
+
+// Object returns the stored object with the device id, if any.
+func (s *Server) Object(deviceId string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.objects[deviceId]
+	return copyEntity(o), ok
+}
+
+// Owner returns the stored owner with the username, if any.
+func (s *Server) Owner(username string) (map[string]interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.owners[username]
+	return copyEntity(o), ok
+}
+
+// DataModel returns the stored data model, like the export of the platform.
+func (s *Server) DataModel() mnubo.DataModel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.model.export()
+}
+
+// handleToken issues access tokens for the client credentials.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != s.ClientID || secret != s.ClientSecret {
+		writeJSON(w, r, http.StatusUnauthorized, map[string]string{
+			"error":             "invalid_client",
+			"error_description": "Bad client credentials",
+		})
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		writeJSON(w, r, http.StatusBadRequest, map[string]string{
+			"error":             "unsupported_grant_type",
+			"error_description": "Only the client_credentials grant type is supported",
+		})
+		return
+	}
+
+	s.mu.Lock()
+	token := s.issueToken()
+	s.mu.Unlock()
+
+	writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   TokenExpiresIn,
+		"scope":        r.PostForm.Get("scope"),
+		"jti":          randomId(),
+	})
+}
+
+// issueToken returns a new access token, with the mutex held.
+func (s *Server) issueToken() string {
+	token := randomId()
+	s.tokens[token] = true
+	return token
+}
+
+// authenticate rejects the requests to the API without a valid access token, and uncompresses request bodies.
+// Every response advertises the supported request encodings.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Encoding", "gzip, deflate, zstd")
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			s.mu.Lock()
+			valid := s.tokens[token]
+			s.mu.Unlock()
+			if !valid {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeJSON(w, r, http.StatusUnauthorized, map[string]string{
+					"error":             "invalid_token",
+					"error_description": "Invalid access token",
+				})
+				return
+			}
+		}
+
+		body, err := decompressor(r.Header.Get("Content-Encoding"), r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Unable to read the request body: %s", err)
+			return
+		}
+		defer body.Close()
+		r.Body = body
+		next.ServeHTTP(w, r)
+	})
+}
+
+// decompressor returns a reader uncompressing body according to a Content-Encoding header.
+func decompressor(encoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	switch encoding {
+	case "":
+		return body, nil
+	case mnubo.EncodingGzip:
+		return gzip.NewReader(body)
+	case mnubo.EncodingDeflate:
+		return zlib.NewReader(body)
+	case mnubo.EncodingZstd:
+		d, err := zstd.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding: %s", encoding)
+	}
+}
+
+// readJSON decodes the JSON body of a request into v, or responds with a 400 error.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON payload: %s", err)
+		return false
+	}
+	return true
+}
+
+// writeJSON responds with v, gzipped if the request accepts it.
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if !strings.Contains(r.Header.Get("Accept-Encoding"), mnubo.EncodingGzip) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", mnubo.EncodingGzip)
+	w.WriteHeader(status)
+	gw := gzip.NewWriter(w)
+	json.NewEncoder(gw).Encode(v)
+	gw.Close()
+}
+
+// writeError responds with a text/plain error message, like the platform.
+func writeError(w http.ResponseWriter, status int, format string, a ...interface{}) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, format, a...)
+}
+
+// randomId returns a random hexadecimal identifier.
+func randomId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// randomCode returns a random challenge code.
+func randomCode() string {
+	b := make([]byte, 9)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// copyEntity returns a shallow copy of an event, object or owner.
+func copyEntity(e map[string]interface{}) map[string]interface{} {
+	if e == nil {
+		return nil
+	}
+	c := make(map[string]interface{}, len(e))
+	for k, v := range e {
+		c[k] = v
+	}
+	return c
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
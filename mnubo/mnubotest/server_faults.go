@@ -0,0 +1,186 @@
+package mnubotest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+// Fault is a platform failure injected by a Server in the responses to the requests it matches.
+// The fields other than Method, Path and Times are combined: a fault can delay a response and truncate it.
+type Fault struct {
+	// Method and Path select the requests affected by the fault: any method if Method is empty, and the
+	// paths starting with Path, like "/api/v3/events".
+	Method string
+	Path   string
+	// Times is the number of matching requests affected by the fault: once if 0, every one if negative.
+	Times int
+
+	// Delay waits before responding, to exceed Mnubo.Timeout.
+	Delay time.Duration
+	// RevokeTokens revokes every access token before authenticating the request, which is rejected with a 401.
+	RevokeTokens bool
+	// StatusCode responds with this error status code instead of handling the request, like 503 or 429.
+	StatusCode int
+	// RetryAfter is sent as the Retry-After header of the StatusCode responses, in seconds, if set.
+	RetryAfter time.Duration
+	// TruncateBody gzips the response, if it isn't already, and drops the second half of the compressed body.
+	TruncateBody bool
+	// RejectEvents are the indexes of the events rejected in the requests sending events, the others
+	// are stored. The rejected events are reported as errors when reporting results, and fail the
+	// request with a 400 otherwise.
+	RejectEvents []int
+
+	// none is set for the faults affecting no request, as Times is 0 for the faults affecting one.
+	none bool
+}
+
+// ServiceUnavailable returns a fault responding 503 to the next n requests, or to every one if n is negative.
+// Injecting it has no effect if n is 0.
+func ServiceUnavailable(n int) Fault {
+	return Fault{Times: n, StatusCode: http.StatusServiceUnavailable, none: n == 0}
+}
+
+// TooManyRequests returns a fault responding 429 with a Retry-After header to the next request.
+func TooManyRequests(retryAfter time.Duration) Fault {
+	return Fault{StatusCode: http.StatusTooManyRequests, RetryAfter: retryAfter}
+}
+
+// Inject adds faults to the server, they are applied in order to the requests they match.
+// A request is affected by the first matching fault only.
+func (s *Server) Inject(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range faults {
+		if f.none {
+			continue
+		}
+		if f.Times == 0 {
+			f.Times = 1
+		}
+		s.faults = append(s.faults, &f)
+	}
+}
+
+// ClearFaults removes the pending faults.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults = nil
+}
+
+// Requests returns the method and path of the requests received by the server, like "POST /api/v3/events",
+// including the ones affected by faults.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+// matches returns true if the fault applies to r.
+func (f *Fault) matches(r *http.Request) bool {
+	return (f.Method == "" || f.Method == r.Method) && strings.HasPrefix(r.URL.Path, f.Path)
+}
+
+// nextFault records r and returns the fault it is affected by, if any.
+func (s *Server) nextFault(r *http.Request) *Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+	for i, f := range s.faults {
+		if !f.matches(r) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = slices.Delete(s.faults, i, i+1)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+type faultKey struct{}
+
+// faultFrom returns the fault affecting the request of ctx, if any.
+func faultFrom(ctx context.Context) *Fault {
+	f, _ := ctx.Value(faultKey{}).(*Fault)
+	return f
+}
+
+// injectFaults applies the faults to the requests they match.
+func (s *Server) injectFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f := s.nextFault(r)
+		if f == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if f.Delay > 0 {
+			select {
+			case <-time.After(f.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if f.RevokeTokens {
+			s.RevokeTokens()
+		}
+
+		rw := w
+		var rec *httptest.ResponseRecorder
+		if f.TruncateBody {
+			rec = httptest.NewRecorder()
+			rw = rec
+		}
+
+		if f.StatusCode != 0 {
+			if f.RetryAfter > 0 {
+				rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(f.RetryAfter.Seconds()))))
+			}
+			writeError(rw, f.StatusCode, "%s", http.StatusText(f.StatusCode))
+		} else {
+			next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), faultKey{}, f)))
+		}
+
+		if rec != nil {
+			writeTruncated(w, rec)
+		}
+	})
+}
+
+// writeTruncated writes the gzipped response recorded by rec, without the second half of its body.
+func writeTruncated(w http.ResponseWriter, rec *httptest.ResponseRecorder) {
+	body := rec.Body.Bytes()
+	if rec.Header().Get("Content-Encoding") != mnubo.EncodingGzip {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		gw.Write(body)
+		gw.Close()
+		body = buf.Bytes()
+	}
+
+	for k, v := range rec.Header() {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Encoding", mnubo.EncodingGzip)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(rec.Code)
+	w.Write(body[:len(body)/2])
+}
@@ -0,0 +1,136 @@
+package mnubotest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+func TestServer_ServiceUnavailable(t *testing.T) {
+	s, m := newServerClient(t)
+	s.Inject(Fault{Path: "/api/v3/events", Times: 2, StatusCode: http.StatusServiceUnavailable})
+
+	events := []serverEvent{{XObject: map[string]string{"x_device_id": "device-1"}, XEventType: "drive"}}
+	if _, err := mnubo.SendEvents(context.Background(), m.Events, events, mnubo.SendEventsOptions{}); err != nil {
+		t.Errorf("expecting the request to be retried, got: %+v", err)
+	}
+	expected := []string{"POST /oauth/token", "POST /api/v3/events", "POST /api/v3/events", "POST /api/v3/events"}
+	if requests := s.Requests(); !slices.Equal(requests, expected) {
+		t.Errorf("expecting: %v, got: %v", expected, requests)
+	}
+
+	// ServiceUnavailable(0) fails no request, unlike a Fault whose Times is 0.
+	s.Inject(ServiceUnavailable(0))
+	if _, err := mnubo.SendEvents(context.Background(), m.Events, events, mnubo.SendEventsOptions{}); err != nil {
+		t.Errorf("expecting no request to fail, got: %+v", err)
+	}
+
+	s.Inject(ServiceUnavailable(-1))
+	m.RetryPolicy.MaxAttempts = 2
+	var apiError *mnubo.APIError
+	if _, err := mnubo.SendEvents(context.Background(), m.Events, events, mnubo.SendEventsOptions{}); !errors.As(err, &apiError) || apiError.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expecting the attempts to fail, got: %+v", err)
+	}
+	if requests := s.Requests(); len(requests) != 7 {
+		t.Errorf("expecting 2 attempts, got: %v", requests)
+	}
+	s.ClearFaults()
+	if len(s.Events()) != 2 {
+		t.Errorf("expecting a single event, got: %+v", s.Events())
+	}
+}
+
+func TestServer_TooManyRequests(t *testing.T) {
+	s, m := newServerClient(t)
+	s.Inject(TooManyRequests(time.Second))
+
+	var delays []time.Duration
+	m.ExponentialBackoff.NotifyOnError = func(err error, next time.Duration) {
+		delays = append(delays, next)
+	}
+	if _, err := m.GetAccessToken(); err != nil {
+		t.Errorf("expecting the request to be retried, got: %+v", err)
+	}
+	if len(delays) != 1 || delays[0] != time.Second {
+		t.Errorf("expecting the Retry-After delay, got: %v", delays)
+	}
+}
+
+func TestServer_Delay(t *testing.T) {
+	s, m := newServerClient(t, mnubo.WithTimeout(50*time.Millisecond), mnubo.WithRetryPolicy(mnubo.RetryPolicy{}))
+	s.Inject(Fault{Path: "/api/", Delay: time.Second})
+
+	var results []mnubo.BatchResult
+	if err := m.Objects.Update([]map[string]string{}, &results); err == nil {
+		t.Error("expecting the request to time out")
+	}
+	if err := m.Objects.Update([]map[string]string{}, &results); err != nil {
+		t.Errorf("expecting the fault to be consumed, got: %+v", err)
+	}
+}
+
+func TestServer_TruncateBody(t *testing.T) {
+	s, m := newServerClient(t, mnubo.WithRetryPolicy(mnubo.RetryPolicy{}))
+	s.Inject(Fault{Path: "/api/v3/objects/exists", TruncateBody: true})
+
+	exist := mnubo.EntitiesExist{}
+	if err := m.Objects.Exist([]string{"device-1"}, &exist); err == nil {
+		t.Errorf("expecting the response to be truncated, got: %+v", exist)
+	}
+	if err := m.Objects.Exist([]string{"device-1"}, &exist); err != nil || len(exist) != 1 {
+		t.Errorf("unexpected existence: %+v (%+v)", exist, err)
+	}
+}
+
+func TestServer_RevokeTokens(t *testing.T) {
+	s, m := newServerClient(t)
+	static := mnubo.NewClientWithToken(s.IssueToken(), s.URL)
+	var results []mnubo.BatchResult
+
+	if err := m.Objects.Update([]map[string]string{}, &results); err != nil {
+		t.Fatalf("unable to update objects: %+v", err)
+	}
+	s.Inject(Fault{Path: "/api/", RevokeTokens: true})
+	if err := m.Objects.Update([]map[string]string{}, &results); err != nil {
+		t.Errorf("expecting the client to get a new token, got: %+v", err)
+	}
+	if err := static.Objects.Update([]map[string]string{}, &results); !mnubo.IsUnauthorized(err) {
+		t.Errorf("expecting the static token to be revoked, got: %+v", err)
+	}
+
+	tokens := 0
+	for _, r := range s.Requests() {
+		if r == "POST /oauth/token" {
+			tokens++
+		}
+	}
+	if tokens != 2 {
+		t.Errorf("expecting 2 token requests, got: %v", s.Requests())
+	}
+}
+
+func TestServer_RejectEvents(t *testing.T) {
+	s, m := newServerClient(t)
+	s.Inject(Fault{Path: "/api/v3/events", Times: 2, RejectEvents: []int{1}})
+
+	events := []serverEvent{
+		{XObject: map[string]string{"x_device_id": "device-1"}, XEventType: "drive"},
+		{XObject: map[string]string{"x_device_id": "device-2"}, XEventType: "drive"},
+		{XObject: map[string]string{"x_device_id": "device-3"}, XEventType: "drive"},
+	}
+	reports, err := mnubo.SendEvents(context.Background(), m.Events, events, mnubo.SendEventsOptions{ReportResults: true})
+	if err != nil || len(reports) != 3 || reports[0].Result != "success" || reports[1].Result != "error" || reports[2].Result != "success" {
+		t.Errorf("unexpected reports: %+v (%+v)", reports, err)
+	}
+	if _, err := mnubo.SendEvents(context.Background(), m.Events, events, mnubo.SendEventsOptions{}); !mnubo.IsBadRequest(err) {
+		t.Errorf("expecting the request to fail, got: %+v", err)
+	}
+	if stored := s.Events(); len(stored) != 4 || stored[1]["x_device_id"] != "device-3" {
+		t.Errorf("unexpected events: %+v", stored)
+	}
+}
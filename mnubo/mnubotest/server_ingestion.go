@@ -0,0 +1,293 @@
+package mnubotest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+// timestampFormat is the format of the x_timestamp of the events received without one.
+const timestampFormat = "2006-01-02T15:04:05.000Z"
+
+// handleIngestion registers the events, objects and owners endpoints.
+func (s *Server) handleIngestion(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v3/events", func(w http.ResponseWriter, r *http.Request) {
+		s.sendEvents(w, r, "")
+	})
+	mux.HandleFunc("POST /api/v3/objects/{deviceId}/events", func(w http.ResponseWriter, r *http.Request) {
+		s.sendEvents(w, r, r.PathValue("deviceId"))
+	})
+	mux.HandleFunc("POST /api/v3/events/exists", func(w http.ResponseWriter, r *http.Request) {
+		s.exist(w, r, func(id string) bool { return s.eventIds[id] })
+	})
+
+	mux.HandleFunc("POST /api/v3/objects", func(w http.ResponseWriter, r *http.Request) {
+		s.createEntity(w, r, s.objects, "x_device_id", "x_object_type")
+	})
+	mux.HandleFunc("PUT /api/v3/objects", func(w http.ResponseWriter, r *http.Request) {
+		s.updateEntities(w, r, s.objects, "x_device_id", "x_object_type")
+	})
+	mux.HandleFunc("DELETE /api/v3/objects/{deviceId}", func(w http.ResponseWriter, r *http.Request) {
+		s.deleteEntity(w, r.PathValue("deviceId"), s.objects, "Object with x_device_id '%s' not found.")
+	})
+	mux.HandleFunc("POST /api/v3/objects/exists", func(w http.ResponseWriter, r *http.Request) {
+		s.exist(w, r, func(id string) bool { return s.objects[id] != nil })
+	})
+
+	mux.HandleFunc("POST /api/v3/owners", func(w http.ResponseWriter, r *http.Request) {
+		s.createEntity(w, r, s.owners, "username", "")
+	})
+	mux.HandleFunc("PUT /api/v3/owners", func(w http.ResponseWriter, r *http.Request) {
+		s.updateEntities(w, r, s.owners, "username", "")
+	})
+	mux.HandleFunc("PUT /api/v3/owners/{username}/password", s.updateOwnerPassword)
+	mux.HandleFunc("DELETE /api/v3/owners/{username}", func(w http.ResponseWriter, r *http.Request) {
+		s.deleteEntity(w, r.PathValue("username"), s.owners, "Owner with username '%s' not found.")
+	})
+	mux.HandleFunc("POST /api/v3/owners/exists", func(w http.ResponseWriter, r *http.Request) {
+		s.exist(w, r, func(id string) bool { return s.owners[id] != nil })
+	})
+	mux.HandleFunc("POST /api/v3/owners/claim", func(w http.ResponseWriter, r *http.Request) {
+		s.claim(w, r, true)
+	})
+	mux.HandleFunc("POST /api/v3/owners/unclaim", func(w http.ResponseWriter, r *http.Request) {
+		s.claim(w, r, false)
+	})
+}
+
+// sendEvents stores events, reporting the result of each one if requested.
+// Events are sent by the object of their x_object, or by deviceId when it is set.
+func (s *Server) sendEvents(w http.ResponseWriter, r *http.Request, deviceId string) {
+	var events []map[string]interface{}
+	if !readJSON(w, r, &events) {
+		return
+	}
+	reportResults := r.URL.Query().Get("report_results") == "true"
+	objectsMustExist := r.URL.Query().Get("objects_must_exist") == "true"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rejected []int
+	if f := faultFrom(r.Context()); f != nil {
+		rejected = f.RejectEvents
+	}
+
+	reports := make([]mnubo.SendEventsReport, 0, len(events))
+	var failure string
+	for i, e := range events {
+		var stored map[string]interface{}
+		var message string
+		if slices.Contains(rejected, i) {
+			stored, message = e, "Injected failure"
+		} else {
+			stored, message = s.storeEvent(e, deviceId, objectsMustExist)
+		}
+		report := mnubo.SendEventsReport{
+			ID:           stringField(stored, "event_id"),
+			Result:       "success",
+			ObjectExists: s.objects[stringField(stored, "x_device_id")] != nil,
+		}
+		if message != "" {
+			report.Result = "error"
+			if failure == "" {
+				failure = message
+			}
+		}
+		reports = append(reports, report)
+	}
+
+	if reportResults {
+		writeJSON(w, r, http.StatusOK, reports)
+	} else if failure != "" {
+		writeError(w, http.StatusBadRequest, "%s", failure)
+	}
+}
+
+// storeEvent validates and stores an event with the mutex held, and returns it with the reason of its rejection, if any.
+func (s *Server) storeEvent(e map[string]interface{}, deviceId string, objectMustExist bool) (map[string]interface{}, string) {
+	stored := copyEntity(e)
+	delete(stored, "x_object")
+	if o, ok := e["x_object"].(map[string]interface{}); ok && deviceId == "" {
+		deviceId, _ = o["x_device_id"].(string)
+	}
+	stored["x_device_id"] = deviceId
+	if stringField(stored, "event_id") == "" {
+		stored["event_id"] = randomId()
+	}
+	if stringField(stored, "x_timestamp") == "" {
+		stored["x_timestamp"] = time.Now().UTC().Format(timestampFormat)
+	}
+
+	id := stringField(stored, "event_id")
+	switch {
+	case deviceId == "":
+		return stored, "x_object.x_device_id is missing"
+	case stringField(stored, "x_event_type") == "":
+		return stored, "x_event_type is missing"
+	case objectMustExist && s.objects[deviceId] == nil:
+		return stored, fmt.Sprintf("Object with x_device_id '%s' not found.", deviceId)
+	case s.eventIds[id]:
+		return stored, fmt.Sprintf("Event with event_id '%s' already exists.", id)
+	}
+	s.events = append(s.events, stored)
+	s.eventIds[id] = true
+	return stored, ""
+}
+
+// createEntity stores a new object or owner identified by its key field.
+// The required field, if any, must be set as well.
+func (s *Server) createEntity(w http.ResponseWriter, r *http.Request, entities map[string]map[string]interface{}, key string, required string) {
+	var e map[string]interface{}
+	if !readJSON(w, r, &e) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := stringField(e, key)
+	switch {
+	case id == "":
+		writeError(w, http.StatusBadRequest, "%s is missing", key)
+	case required != "" && stringField(e, required) == "":
+		writeError(w, http.StatusBadRequest, "%s is missing", required)
+	case entities[id] != nil:
+		writeError(w, http.StatusConflict, "%s '%s' already exists.", key, id)
+	default:
+		entities[id] = e
+		writeJSON(w, r, http.StatusCreated, e)
+	}
+}
+
+// updateEntities creates or updates a batch of objects or owners, reporting the result of each one.
+func (s *Server) updateEntities(w http.ResponseWriter, r *http.Request, entities map[string]map[string]interface{}, key string, required string) {
+	var batch []map[string]interface{}
+	if !readJSON(w, r, &batch) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]mnubo.BatchResult, 0, len(batch))
+	for _, e := range batch {
+		id := stringField(e, key)
+		result := mnubo.BatchResult{ID: id, Result: "success"}
+		existing := entities[id]
+		switch {
+		case id == "":
+			result.Result, result.Message = "error", fmt.Sprintf("%s is missing", key)
+		case existing == nil && required != "" && stringField(e, required) == "":
+			result.Result, result.Message = "error", fmt.Sprintf("%s is missing", required)
+		case existing == nil:
+			entities[id] = e
+		default:
+			updated := copyEntity(existing)
+			for k, v := range e {
+				updated[k] = v
+			}
+			entities[id] = updated
+		}
+		results = append(results, result)
+	}
+	writeJSON(w, r, http.StatusOK, results)
+}
+
+// deleteEntity deletes the object or owner with the id.
+func (s *Server) deleteEntity(w http.ResponseWriter, id string, entities map[string]map[string]interface{}, notFound string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entities[id] == nil {
+		writeError(w, http.StatusNotFound, notFound, id)
+		return
+	}
+	delete(entities, id)
+}
+
+// exist reports whether each of the ids of the request exists, in order.
+func (s *Server) exist(w http.ResponseWriter, r *http.Request, exists func(id string) bool) {
+	var ids []string
+	if !readJSON(w, r, &ids) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]map[string]bool, 0, len(ids))
+	for _, id := range ids {
+		results = append(results, map[string]bool{id: exists(id)})
+	}
+	writeJSON(w, r, http.StatusOK, results)
+}
+
+func (s *Server) updateOwnerPassword(w http.ResponseWriter, r *http.Request) {
+	var payload mnubo.PasswordUpdatePayload
+	if !readJSON(w, r, &payload) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	username := r.PathValue("username")
+	owner := s.owners[username]
+	switch {
+	case owner == nil:
+		writeError(w, http.StatusNotFound, "Owner with username '%s' not found.", username)
+	case payload.XPassword == "":
+		writeError(w, http.StatusBadRequest, "x_password is missing")
+	default:
+		owner["x_password"] = payload.XPassword
+	}
+}
+
+// claim claims or unclaims a batch of objects, reporting the result of each one.
+// The x_owner of claimed objects is set to the username of their owner.
+func (s *Server) claim(w http.ResponseWriter, r *http.Request, claim bool) {
+	var pairs []mnubo.ObjectOwnerPair
+	if !readJSON(w, r, &pairs) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]mnubo.ClaimResult, 0, len(pairs))
+	for _, p := range pairs {
+		result := mnubo.ClaimResult{ID: p.XDeviceID, Result: "success"}
+		object := s.objects[p.XDeviceID]
+		var owner string
+		if object != nil {
+			owner = stringField(object["x_owner"], "username")
+		}
+		switch {
+		case object == nil:
+			result.Result, result.Message = "error", fmt.Sprintf("Object with x_device_id '%s' not found.", p.XDeviceID)
+		case s.owners[p.Username] == nil:
+			result.Result, result.Message = "error", fmt.Sprintf("Owner with username '%s' not found.", p.Username)
+		case claim && owner != "" && owner != p.Username:
+			result.Result, result.Message = "error", fmt.Sprintf("Object with x_device_id '%s' is already claimed by '%s'.", p.XDeviceID, owner)
+		case claim:
+			object["x_owner"] = map[string]interface{}{"username": p.Username}
+		case owner != p.Username:
+			result.Result, result.Message = "error", fmt.Sprintf("Object with x_device_id '%s' is not claimed by '%s'.", p.XDeviceID, p.Username)
+		default:
+			delete(object, "x_owner")
+		}
+		results = append(results, result)
+	}
+	writeJSON(w, r, http.StatusOK, results)
+}
+
+// stringField returns the field of an entity if it is a string, or an empty string.
+func stringField(e interface{}, field string) string {
+	m, _ := e.(map[string]interface{})
+	s, _ := m[field].(string)
+	return s
+}
@@ -0,0 +1,381 @@
+package mnubotest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+const modelPath = "/api/v3/model"
+
+// model is the data model stored by a Server, the relations are kept in the timeseries and object attributes.
+type model struct {
+	timeseries       map[string]*mnubo.Timeseries
+	objectAttributes map[string]*mnubo.ObjectAttribute
+	ownerAttributes  map[string]*mnubo.OwnerAttribute
+	eventTypes       map[string]*mnubo.EventType
+	objectTypes      map[string]*mnubo.ObjectType
+	// deployed are the entities deployed to production, like "timeseries/speed".
+	deployed map[string]bool
+}
+
+func newModel() *model {
+	return &model{
+		timeseries:       make(map[string]*mnubo.Timeseries),
+		objectAttributes: make(map[string]*mnubo.ObjectAttribute),
+		ownerAttributes:  make(map[string]*mnubo.OwnerAttribute),
+		eventTypes:       make(map[string]*mnubo.EventType),
+		objectTypes:      make(map[string]*mnubo.ObjectType),
+		deployed:         make(map[string]bool),
+	}
+}
+
+// eventType returns an event type with the keys of its timeseries.
+func (m *model) eventType(et *mnubo.EventType) mnubo.EventType {
+	e := *et
+	e.TimeseriesKeys, e.Timeseries = nil, nil
+	for _, k := range sortedKeys(m.timeseries) {
+		if slices.Contains(m.timeseries[k].EventTypeKeys, et.Key) {
+			e.TimeseriesKeys = append(e.TimeseriesKeys, k)
+		}
+	}
+	return e
+}
+
+// objectType returns an object type with the keys of its object attributes.
+func (m *model) objectType(ot *mnubo.ObjectType) mnubo.ObjectType {
+	o := *ot
+	o.ObjectAttributesKeys, o.ObjectAttributes = nil, nil
+	for _, k := range sortedKeys(m.objectAttributes) {
+		if slices.Contains(m.objectAttributes[k].ObjectTypeKeys, ot.Key) {
+			o.ObjectAttributesKeys = append(o.ObjectAttributesKeys, k)
+		}
+	}
+	return o
+}
+
+// export returns the data model with the entities nested in their types, like the platform export.
+func (m *model) export() mnubo.DataModel {
+	dm := mnubo.DataModel{
+		ObjectTypes:     []mnubo.ObjectType{},
+		EventTypes:      []mnubo.EventType{},
+		OwnerAttributes: []mnubo.OwnerAttribute{},
+		Sessionizers:    []mnubo.Sessionizer{},
+	}
+	for _, k := range sortedKeys(m.eventTypes) {
+		et := m.eventType(m.eventTypes[k])
+		for _, ts := range et.TimeseriesKeys {
+			et.Timeseries = append(et.Timeseries, *m.timeseries[ts])
+		}
+		dm.EventTypes = append(dm.EventTypes, et)
+	}
+	for _, k := range sortedKeys(m.objectTypes) {
+		ot := m.objectType(m.objectTypes[k])
+		for _, oa := range ot.ObjectAttributesKeys {
+			ot.ObjectAttributes = append(ot.ObjectAttributes, *m.objectAttributes[oa])
+		}
+		dm.ObjectTypes = append(dm.ObjectTypes, ot)
+	}
+	for _, k := range sortedKeys(m.ownerAttributes) {
+		dm.OwnerAttributes = append(dm.OwnerAttributes, *m.ownerAttributes[k])
+	}
+	for _, k := range sortedKeys(m.timeseries) {
+		if len(m.timeseries[k].EventTypeKeys) == 0 {
+			dm.Orphans.Timeseries = append(dm.Orphans.Timeseries, *m.timeseries[k])
+		}
+	}
+	return dm
+}
+
+// modelEntities serves the creation, update and listing of one kind of entity of the data model.
+type modelEntities[T any] struct {
+	// path is the path of the entities, relative to the model path.
+	path string
+	// entities returns the stored entities of a model.
+	entities func(m *model) map[string]*T
+	// fields returns the key, display name and description of an entity.
+	fields func(e *T) (key string, displayName *string, description *string)
+	// validate returns why an entity can't be created, if it can't.
+	validate func(m *model, e *T) string
+	// view returns an entity as it is listed, the entity itself when nil.
+	view func(m *model, e *T) T
+	// deployable entities can be deployed to production with a challenge code.
+	deployable bool
+}
+
+func (me modelEntities[T]) register(s *Server, mux *http.ServeMux) {
+	path := modelPath + "/" + me.path
+
+	mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		entities := me.entities(s.model)
+		results := make([]T, 0, len(entities))
+		for _, k := range sortedKeys(entities) {
+			if me.view != nil {
+				results = append(results, me.view(s.model, entities[k]))
+			} else {
+				results = append(results, *entities[k])
+			}
+		}
+		writeJSON(w, r, http.StatusOK, results)
+	})
+
+	mux.HandleFunc("POST "+path, func(w http.ResponseWriter, r *http.Request) {
+		var batch []T
+		if !readJSON(w, r, &batch) {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		entities := me.entities(s.model)
+		created := make(map[string]bool)
+		for i := range batch {
+			key, _, _ := me.fields(&batch[i])
+			switch {
+			case key == "":
+				writeError(w, http.StatusBadRequest, "key is missing")
+				return
+			case entities[key] != nil || created[key]:
+				writeError(w, http.StatusConflict, "%s '%s' already exists.", me.path, key)
+				return
+			}
+			if message := me.validate(s.model, &batch[i]); message != "" {
+				writeError(w, http.StatusBadRequest, "%s", message)
+				return
+			}
+			created[key] = true
+		}
+		for i := range batch {
+			key, _, _ := me.fields(&batch[i])
+			entities[key] = &batch[i]
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	mux.HandleFunc("PUT "+path+"/{key}", func(w http.ResponseWriter, r *http.Request) {
+		var update T
+		if !readJSON(w, r, &update) {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		e := me.entities(s.model)[r.PathValue("key")]
+		if e == nil {
+			writeError(w, http.StatusNotFound, "%s '%s' not found.", me.path, r.PathValue("key"))
+			return
+		}
+		_, displayName, description := me.fields(e)
+		_, newDisplayName, newDescription := me.fields(&update)
+		*displayName, *description = *newDisplayName, *newDescription
+	})
+
+	if !me.deployable {
+		return
+	}
+
+	mux.HandleFunc("POST "+path+"/{key}/deploy", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		key := r.PathValue("key")
+		if me.entities(s.model)[key] == nil {
+			writeError(w, http.StatusNotFound, "%s '%s' not found.", me.path, key)
+			return
+		}
+		code := randomCode()
+		s.challenges[code] = me.path + "/" + key
+		writeJSON(w, r, http.StatusOK, mnubo.ChallengeCode{Code: code})
+	})
+
+	mux.HandleFunc("POST "+path+"/{key}/deploy/{code}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		entity := me.path + "/" + r.PathValue("key")
+		if s.challenges[r.PathValue("code")] != entity {
+			writeError(w, http.StatusBadRequest, "Invalid challenge code.")
+			return
+		}
+		delete(s.challenges, r.PathValue("code"))
+		s.model.deployed[entity] = true
+	})
+}
+
+// handleModel registers the data model endpoints.
+func (s *Server) handleModel(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+modelPath+"/export", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		writeJSON(w, r, http.StatusOK, s.model.export())
+	})
+
+	modelEntities[mnubo.Timeseries]{
+		path:     "timeseries",
+		entities: func(m *model) map[string]*mnubo.Timeseries { return m.timeseries },
+		fields: func(e *mnubo.Timeseries) (string, *string, *string) {
+			return e.Key, &e.DisplayName, &e.Description
+		},
+		validate: func(m *model, e *mnubo.Timeseries) string {
+			if e.Type.HighLevelType == "" {
+				return "type.highLevelType is missing"
+			}
+			return missingKeys(m.eventTypes, e.EventTypeKeys, "event type")
+		},
+		deployable: true,
+	}.register(s, mux)
+
+	modelEntities[mnubo.ObjectAttribute]{
+		path:     "objectAttributes",
+		entities: func(m *model) map[string]*mnubo.ObjectAttribute { return m.objectAttributes },
+		fields: func(e *mnubo.ObjectAttribute) (string, *string, *string) {
+			return e.Key, &e.DisplayName, &e.Description
+		},
+		validate: func(m *model, e *mnubo.ObjectAttribute) string {
+			if e.Type.HighLevelType == "" {
+				return "type.highLevelType is missing"
+			}
+			return missingKeys(m.objectTypes, e.ObjectTypeKeys, "object type")
+		},
+		deployable: true,
+	}.register(s, mux)
+
+	modelEntities[mnubo.OwnerAttribute]{
+		path:     "ownerAttributes",
+		entities: func(m *model) map[string]*mnubo.OwnerAttribute { return m.ownerAttributes },
+		fields: func(e *mnubo.OwnerAttribute) (string, *string, *string) {
+			return e.Key, &e.DisplayName, &e.Description
+		},
+		validate: func(m *model, e *mnubo.OwnerAttribute) string {
+			if e.Type.HighLevelType == "" {
+				return "type.highLevelType is missing"
+			}
+			return ""
+		},
+		deployable: true,
+	}.register(s, mux)
+
+	modelEntities[mnubo.EventType]{
+		path:     "eventTypes",
+		entities: func(m *model) map[string]*mnubo.EventType { return m.eventTypes },
+		fields: func(e *mnubo.EventType) (string, *string, *string) {
+			return e.Key, &e.DisplayName, &e.Description
+		},
+		validate: func(m *model, e *mnubo.EventType) string { return "" },
+		view:     (*model).eventType,
+	}.register(s, mux)
+
+	modelEntities[mnubo.ObjectType]{
+		path:     "objectTypes",
+		entities: func(m *model) map[string]*mnubo.ObjectType { return m.objectTypes },
+		fields: func(e *mnubo.ObjectType) (string, *string, *string) {
+			return e.Key, &e.DisplayName, &e.Description
+		},
+		validate: func(m *model, e *mnubo.ObjectType) string { return "" },
+		view:     (*model).objectType,
+	}.register(s, mux)
+
+	mux.HandleFunc("DELETE "+modelPath+"/eventTypes/{key}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		key := r.PathValue("key")
+		if s.model.eventTypes[key] == nil {
+			writeError(w, http.StatusNotFound, "eventTypes '%s' not found.", key)
+			return
+		}
+		delete(s.model.eventTypes, key)
+		for _, ts := range s.model.timeseries {
+			ts.EventTypeKeys = slices.DeleteFunc(ts.EventTypeKeys, func(k string) bool { return k == key })
+		}
+	})
+	mux.HandleFunc("DELETE "+modelPath+"/objectTypes/{key}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		key := r.PathValue("key")
+		if s.model.objectTypes[key] == nil {
+			writeError(w, http.StatusNotFound, "objectTypes '%s' not found.", key)
+			return
+		}
+		delete(s.model.objectTypes, key)
+		for _, oa := range s.model.objectAttributes {
+			oa.ObjectTypeKeys = slices.DeleteFunc(oa.ObjectTypeKeys, func(k string) bool { return k == key })
+		}
+	})
+
+	timeseriesRelation := func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		ts := s.model.timeseries[r.PathValue("key")]
+		if s.model.eventTypes[r.PathValue("typeKey")] == nil || ts == nil {
+			writeError(w, http.StatusNotFound, "Event type '%s' or timeseries '%s' not found.", r.PathValue("typeKey"), r.PathValue("key"))
+			return
+		}
+		ts.EventTypeKeys = relate(r.Method, ts.EventTypeKeys, r.PathValue("typeKey"))
+	}
+	mux.HandleFunc("POST "+modelPath+"/eventTypes/{typeKey}/timeseries/{key}", timeseriesRelation)
+	mux.HandleFunc("DELETE "+modelPath+"/eventTypes/{typeKey}/timeseries/{key}", timeseriesRelation)
+
+	objectAttributeRelation := func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		oa := s.model.objectAttributes[r.PathValue("key")]
+		if s.model.objectTypes[r.PathValue("typeKey")] == nil || oa == nil {
+			writeError(w, http.StatusNotFound, "Object type '%s' or object attribute '%s' not found.", r.PathValue("typeKey"), r.PathValue("key"))
+			return
+		}
+		oa.ObjectTypeKeys = relate(r.Method, oa.ObjectTypeKeys, r.PathValue("typeKey"))
+	}
+	mux.HandleFunc("POST "+modelPath+"/objectTypes/{typeKey}/objectAttributes/{key}", objectAttributeRelation)
+	mux.HandleFunc("DELETE "+modelPath+"/objectTypes/{typeKey}/objectAttributes/{key}", objectAttributeRelation)
+
+	mux.HandleFunc("POST "+modelPath+"/reset", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		code := randomCode()
+		s.challenges[code] = "reset"
+		writeJSON(w, r, http.StatusOK, mnubo.ChallengeCode{Code: code})
+	})
+	mux.HandleFunc("POST "+modelPath+"/reset/{code}", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.challenges[r.PathValue("code")] != "reset" {
+			writeError(w, http.StatusBadRequest, "Invalid challenge code.")
+			return
+		}
+		delete(s.challenges, r.PathValue("code"))
+		s.model = newModel()
+	})
+}
+
+// relate adds key to keys for POST requests, and removes it for DELETE requests.
+func relate(method string, keys []string, key string) []string {
+	keys = slices.DeleteFunc(keys, func(k string) bool { return k == key })
+	if method == http.MethodPost {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// missingKeys returns an error message if some keys are not in entities.
+func missingKeys[V any](entities map[string]V, keys []string, kind string) string {
+	for _, k := range keys {
+		if _, ok := entities[k]; !ok {
+			return fmt.Sprintf("%s '%s' not found.", kind, k)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,487 @@
+package mnubotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+const searchPath = "/api/v3/search"
+
+// datasets are the datasets which can be queried, in the order they are listed.
+var datasets = []string{"event", "object", "owner"}
+
+// query is the subset of MQL supported by the server: a select of values or count, sum, avg, min and max
+// aggregations, with an optional where, groupBy and limit. Conditions are combined with and / or, and
+// compare fields with eq, ne, gt, gte, lt, lte, in, startsWith, contains, isNull and isNotNull.
+// Rows are returned in the order the entities were received, groups in the order they were first seen.
+type query struct {
+	From    string                   `json:"from"`
+	Select  []map[string]interface{} `json:"select"`
+	Where   map[string]interface{}   `json:"where,omitempty"`
+	GroupBy []string                 `json:"groupBy,omitempty"`
+	Limit   int                      `json:"limit,omitempty"`
+}
+
+// selection is a parsed select item.
+type selection struct {
+	// op is "value" or an aggregation.
+	op    string
+	field string
+	label string
+}
+
+// handleSearch registers the search endpoints.
+func (s *Server) handleSearch(mux *http.ServeMux) {
+	mux.HandleFunc("POST "+searchPath+"/basic", func(w http.ResponseWriter, r *http.Request) {
+		q, selections, err := parseQuery(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		results, err := s.search(q, selections)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "%s", err)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, results)
+	})
+
+	mux.HandleFunc("POST "+searchPath+"/validateQuery", func(w http.ResponseWriter, r *http.Request) {
+		validation := mnubo.QueryValidation{IsValid: true, ValidationErrors: []string{}}
+		if _, _, err := parseQuery(r.Body); err != nil {
+			validation.IsValid = false
+			validation.ValidationErrors = append(validation.ValidationErrors, err.Error())
+		}
+		writeJSON(w, r, http.StatusOK, validation)
+	})
+
+	mux.HandleFunc("GET "+searchPath+"/datasets", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		results := make([]map[string]interface{}, 0, len(datasets))
+		for _, d := range datasets {
+			entities := s.dataset(d)
+			types := make(map[string]string)
+			for _, e := range entities {
+				for k, v := range e {
+					if t := valueType(v); t != "" {
+						types[k] = t
+					}
+				}
+			}
+			fields := make([]map[string]interface{}, 0, len(types))
+			for _, k := range sortedKeys(types) {
+				fields = append(fields, map[string]interface{}{
+					"key":           k,
+					"highLevelType": types[k],
+					"displayName":   k,
+					"containerType": "none",
+					"primaryKey":    k == primaryKey(d),
+				})
+			}
+			results = append(results, map[string]interface{}{
+				"key":         d,
+				"displayName": d,
+				"fields":      fields,
+			})
+		}
+		writeJSON(w, r, http.StatusOK, results)
+	})
+}
+
+// parseQuery parses and validates a query.
+func parseQuery(r io.Reader) (query, []selection, error) {
+	var q query
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&q); err != nil {
+		return q, nil, fmt.Errorf("invalid or unsupported query: %s", err)
+	}
+	if !slices.Contains(datasets, q.From) {
+		return q, nil, fmt.Errorf("unknown dataset '%s', use %s", q.From, strings.Join(datasets, ", "))
+	}
+	if len(q.Select) == 0 {
+		return q, nil, fmt.Errorf("select is missing")
+	}
+
+	var selections []selection
+	aggregated := len(q.GroupBy) > 0
+	for _, item := range q.Select {
+		sel, err := parseSelection(item)
+		if err != nil {
+			return q, nil, err
+		}
+		if sel.op != "value" {
+			aggregated = true
+		}
+		selections = append(selections, sel)
+	}
+	for _, sel := range selections {
+		if aggregated && sel.op == "value" && !slices.Contains(q.GroupBy, sel.field) {
+			return q, nil, fmt.Errorf("'%s' must be in groupBy to be selected with aggregations", sel.field)
+		}
+	}
+	if q.Where != nil {
+		if _, err := matches(q.Where, nil); err != nil {
+			return q, nil, err
+		}
+	}
+	if q.Limit < 0 {
+		return q, nil, fmt.Errorf("limit must not be negative")
+	}
+	return q, selections, nil
+}
+
+// parseSelection parses a select item like {"value": "speed"}, {"count": "*"} or {"avg": "speed", "as": "speed"}.
+func parseSelection(item map[string]interface{}) (selection, error) {
+	var sel selection
+	for k, v := range item {
+		field, ok := v.(string)
+		if !ok || field == "" {
+			return sel, fmt.Errorf("invalid select item: %v", item)
+		}
+		switch k {
+		case "as":
+			sel.label = field
+		case "value", "count", "sum", "avg", "min", "max":
+			if sel.op != "" {
+				return sel, fmt.Errorf("invalid select item: %v", item)
+			}
+			sel.op, sel.field = k, field
+		default:
+			return sel, fmt.Errorf("unsupported select operation '%s'", k)
+		}
+	}
+	switch {
+	case sel.op == "":
+		return sel, fmt.Errorf("invalid select item: %v", item)
+	case sel.field == "*" && sel.op != "count":
+		return sel, fmt.Errorf("only count can select *")
+	case sel.label != "":
+	case sel.op == "value":
+		sel.label = sel.field
+	default:
+		sel.label = fmt.Sprintf("%s(%s)", strings.ToUpper(sel.op), sel.field)
+	}
+	return sel, nil
+}
+
+// search runs a parsed query over the stored data, with the mutex held.
+func (s *Server) search(q query, selections []selection) (mnubo.SearchResults, error) {
+	var matching []map[string]interface{}
+	for _, e := range s.dataset(q.From) {
+		ok, err := matches(q.Where, e)
+		if err != nil {
+			return mnubo.SearchResults{}, err
+		}
+		if ok {
+			matching = append(matching, e)
+		}
+	}
+
+	aggregated := len(q.GroupBy) > 0
+	for _, sel := range selections {
+		aggregated = aggregated || sel.op != "value"
+	}
+
+	// Without aggregations each entity is a group, otherwise entities are grouped by the groupBy values.
+	var groups [][]map[string]interface{}
+	if !aggregated {
+		for _, e := range matching {
+			groups = append(groups, []map[string]interface{}{e})
+		}
+	} else {
+		index := make(map[string]int)
+		for _, e := range matching {
+			values := make([]interface{}, len(q.GroupBy))
+			for i, f := range q.GroupBy {
+				values[i] = e[f]
+			}
+			key, _ := json.Marshal(values)
+			i, ok := index[string(key)]
+			if !ok {
+				i = len(groups)
+				index[string(key)] = i
+				groups = append(groups, nil)
+			}
+			groups[i] = append(groups[i], e)
+		}
+		if len(groups) == 0 && len(q.GroupBy) == 0 {
+			groups = append(groups, nil)
+		}
+	}
+	if q.Limit > 0 && len(groups) > q.Limit {
+		groups = groups[:q.Limit]
+	}
+
+	results := mnubo.SearchResults{
+		Columns: make([]mnubo.SearchResultsColumn, len(selections)),
+		Rows:    make([][]interface{}, 0, len(groups)),
+	}
+	for _, group := range groups {
+		row := make([]interface{}, len(selections))
+		for i, sel := range selections {
+			row[i] = aggregate(sel, group)
+			if t := valueType(row[i]); results.Columns[i].Type == "" && t != "" {
+				results.Columns[i].Type = t
+			}
+		}
+		results.Rows = append(results.Rows, row)
+	}
+	for i, sel := range selections {
+		results.Columns[i].Label = sel.label
+		switch {
+		case sel.op == "count":
+			results.Columns[i].Type = "LONG"
+		case sel.op == "sum" || sel.op == "avg":
+			results.Columns[i].Type = "DOUBLE"
+		case results.Columns[i].Type == "":
+			results.Columns[i].Type = "TEXT"
+		}
+	}
+	return results, nil
+}
+
+// dataset returns the entities of a dataset, with the mutex held.
+func (s *Server) dataset(name string) []map[string]interface{} {
+	switch name {
+	case "event":
+		return s.events
+	case "object":
+		var objects []map[string]interface{}
+		for _, k := range sortedKeys(s.objects) {
+			o := copyEntity(s.objects[k])
+			if owner := stringField(o["x_owner"], "username"); owner != "" {
+				o["x_owner.username"] = owner
+			}
+			delete(o, "x_owner")
+			objects = append(objects, o)
+		}
+		return objects
+	case "owner":
+		var owners []map[string]interface{}
+		for _, k := range sortedKeys(s.owners) {
+			o := copyEntity(s.owners[k])
+			delete(o, "x_password")
+			owners = append(owners, o)
+		}
+		return owners
+	}
+	return nil
+}
+
+// primaryKey returns the primary key field of a dataset.
+func primaryKey(dataset string) string {
+	switch dataset {
+	case "event":
+		return "event_id"
+	case "object":
+		return "x_device_id"
+	default:
+		return "username"
+	}
+}
+
+// aggregate returns the value of a selection for a group of entities.
+func aggregate(sel selection, group []map[string]interface{}) interface{} {
+	if sel.op == "value" {
+		if len(group) == 0 {
+			return nil
+		}
+		return group[0][sel.field]
+	}
+
+	count := 0
+	var sum float64
+	var result interface{}
+	for _, e := range group {
+		if sel.field == "*" {
+			count++
+			continue
+		}
+		v, ok := e[sel.field]
+		if !ok || v == nil {
+			continue
+		}
+		count++
+		if n, ok := v.(float64); ok {
+			sum += n
+		}
+		if result == nil ||
+			(sel.op == "min" && compare(v, result) < 0) ||
+			(sel.op == "max" && compare(v, result) > 0) {
+			result = v
+		}
+	}
+
+	switch sel.op {
+	case "count":
+		return count
+	case "sum":
+		return sum
+	case "avg":
+		if count == 0 {
+			return nil
+		}
+		return sum / float64(count)
+	default:
+		return result
+	}
+}
+
+// matches returns true if an entity matches a where condition. The condition is only validated if e is nil.
+func matches(where map[string]interface{}, e map[string]interface{}) (bool, error) {
+	if where == nil {
+		return true, nil
+	}
+	if len(where) != 1 {
+		return false, fmt.Errorf("invalid condition, expecting a single field, and or or: %v", where)
+	}
+
+	for k, v := range where {
+		if k == "and" || k == "or" {
+			conditions, ok := v.([]interface{})
+			if !ok {
+				return false, fmt.Errorf("invalid %s condition, expecting a list: %v", k, v)
+			}
+			result := k == "and"
+			for _, c := range conditions {
+				condition, ok := c.(map[string]interface{})
+				if !ok {
+					return false, fmt.Errorf("invalid condition: %v", c)
+				}
+				ok, err := matches(condition, e)
+				if err != nil {
+					return false, err
+				}
+				if k == "and" {
+					result = result && ok
+				} else {
+					result = result || ok
+				}
+			}
+			return result, nil
+		}
+
+		operations, ok := v.(map[string]interface{})
+		if !ok || len(operations) != 1 {
+			return false, fmt.Errorf("invalid condition on '%s', expecting a single operation: %v", k, v)
+		}
+		for op, operand := range operations {
+			return compareField(op, e[k], operand, e == nil)
+		}
+	}
+	return false, nil
+}
+
+// compareField applies a where operation to the value of a field.
+func compareField(op string, value interface{}, operand interface{}, validateOnly bool) (bool, error) {
+	switch op {
+	case "eq", "ne", "gt", "gte", "lt", "lte", "startsWith", "contains", "isNull", "isNotNull":
+	case "in":
+		if _, ok := operand.([]interface{}); !ok {
+			return false, fmt.Errorf("in expects a list: %v", operand)
+		}
+	default:
+		return false, fmt.Errorf("unsupported where operation '%s'", op)
+	}
+	if validateOnly {
+		return true, nil
+	}
+
+	switch op {
+	case "isNull":
+		return value == nil, nil
+	case "isNotNull":
+		return value != nil, nil
+	case "in":
+		for _, o := range operand.([]interface{}) {
+			if value != nil && compare(value, o) == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "startsWith", "contains":
+		v, ok1 := value.(string)
+		o, ok2 := operand.(string)
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		if op == "startsWith" {
+			return strings.HasPrefix(v, o), nil
+		}
+		return strings.Contains(v, o), nil
+	}
+
+	if value == nil {
+		return false, nil
+	}
+	c := compare(value, operand)
+	switch op {
+	case "eq":
+		return c == 0, nil
+	case "ne":
+		return c != 0, nil
+	case "gt":
+		return c > 0, nil
+	case "gte":
+		return c >= 0, nil
+	case "lt":
+		return c < 0, nil
+	default:
+		return c <= 0, nil
+	}
+}
+
+// compare compares numbers, strings and booleans, values of different types are compared by their JSON encoding.
+func compare(a interface{}, b interface{}) int {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Compare(ja, jb)
+}
+
+// valueType returns the high level type of a value, or an empty string for null values.
+func valueType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "TEXT"
+	case float64:
+		return "DOUBLE"
+	case int:
+		return "LONG"
+	case bool:
+		return "BOOLEAN"
+	case nil:
+		return ""
+	default:
+		return "JSON"
+	}
+}
@@ -0,0 +1,234 @@
+package mnubotest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+)
+
+type serverEvent struct {
+	XObject    map[string]string `json:"x_object"`
+	XEventType string            `json:"x_event_type"`
+	Speed      float64           `json:"speed"`
+}
+
+func newServerClient(t *testing.T, opts ...mnubo.Option) (*Server, *mnubo.Mnubo) {
+	s := NewServer()
+	t.Cleanup(s.Close)
+	m, err := s.NewClient(opts...)
+	if err != nil {
+		t.Fatalf("unable to create the client: %+v", err)
+	}
+	return s, m
+}
+
+func TestServer_Ingestion(t *testing.T) {
+	s, m := newServerClient(t, mnubo.WithCompression(mnubo.CompressionConfig{Request: true, Response: true, Encoding: mnubo.EncodingZstd}))
+	ctx := context.Background()
+
+	object := map[string]string{"x_device_id": "device-1", "x_object_type": "car"}
+	if err := mnubo.CreateObject(ctx, m.Objects, object); err != nil {
+		t.Fatalf("unable to create the object: %+v", err)
+	}
+	if err := mnubo.CreateObject(ctx, m.Objects, object); !mnubo.IsConflict(err) {
+		t.Errorf("expecting a conflict, got: %+v", err)
+	}
+
+	events := []serverEvent{
+		{XObject: map[string]string{"x_device_id": "device-1"}, XEventType: "drive", Speed: 10},
+		{XObject: map[string]string{"x_device_id": "device-2"}, XEventType: "drive", Speed: 20},
+	}
+	reports, err := mnubo.SendEvents(ctx, m.Events, events, mnubo.SendEventsOptions{ReportResults: true, ObjectsMustExist: true})
+	if err != nil || len(reports) != 2 || reports[0].Result != "success" || reports[1].Result != "error" || reports[1].ObjectExists {
+		t.Errorf("unexpected reports: %+v (%+v)", reports, err)
+	}
+	if err := m.Events.Send(events[1:], mnubo.SendEventsOptions{}, nil); err != nil {
+		t.Errorf("unable to send events: %+v", err)
+	}
+	if _, err := mnubo.SendEventsFromDevice(ctx, m.Events, "device-3", []map[string]string{{}}, mnubo.SendEventsOptions{}); !mnubo.IsBadRequest(err) {
+		t.Errorf("expecting events without type to be rejected, got: %+v", err)
+	}
+	stored := s.Events()
+	if len(stored) != 2 || stored[1]["x_device_id"] != "device-2" || stored[1]["speed"] != 20.0 {
+		t.Errorf("unexpected events: %+v", stored)
+	}
+
+	exist := mnubo.EntitiesExist{}
+	if err := m.Events.Exists([]string{stored[0]["event_id"].(string), "unknown"}, &exist); err != nil || len(exist) != 2 || !exist[stored[0]["event_id"].(string)] || exist["unknown"] {
+		t.Errorf("unexpected events existence: %+v (%+v)", exist, err)
+	}
+
+	results, err := mnubo.UpdateObjects(ctx, m.Objects, []map[string]string{{"x_device_id": "device-1", "color": "red"}, {"color": "blue"}})
+	if err != nil || len(results) != 2 || results[0].Result != "success" || results[1].Result != "error" {
+		t.Errorf("unexpected update results: %+v (%+v)", results, err)
+	}
+	if o, _ := s.Object("device-1"); o["color"] != "red" || o["x_object_type"] != "car" {
+		t.Errorf("expecting the object to be updated, got: %+v", o)
+	}
+
+	if err := mnubo.CreateOwner(ctx, m.Owners, map[string]string{"username": "owner-1"}); err != nil {
+		t.Fatalf("unable to create the owner: %+v", err)
+	}
+	if err := m.Owners.UpdateOwnerPassword("owner-1", "secret"); err != nil {
+		t.Errorf("unable to update the password: %+v", err)
+	}
+	var claims []mnubo.ClaimResult
+	pairs := []mnubo.ObjectOwnerPair{{XDeviceID: "device-1", Username: "owner-1"}, {XDeviceID: "device-2", Username: "owner-1"}}
+	if err := m.Owners.Claim(pairs, &claims); err != nil || claims[0].Result != "success" || claims[1].Result != "error" {
+		t.Errorf("unexpected claim results: %+v (%+v)", claims, err)
+	}
+	if o, _ := s.Object("device-1"); stringField(o["x_owner"], "username") != "owner-1" {
+		t.Errorf("expecting the object to be claimed, got: %+v", o)
+	}
+	if err := m.Owners.Unclaim(pairs[:1], &claims); err != nil || claims[0].Result != "success" {
+		t.Errorf("unexpected unclaim results: %+v (%+v)", claims, err)
+	}
+
+	if err := m.Owners.Delete("owner-1"); err != nil {
+		t.Errorf("unable to delete the owner: %+v", err)
+	}
+	if err := m.Objects.Delete("device-1"); err != nil {
+		t.Errorf("unable to delete the object: %+v", err)
+	}
+	if err := m.Objects.Delete("device-1"); !mnubo.IsNotFound(err) {
+		t.Errorf("expecting the object to be deleted, got: %+v", err)
+	}
+	exist = mnubo.EntitiesExist{}
+	if err := m.Owners.Exist([]string{"owner-1"}, &exist); err != nil || exist["owner-1"] {
+		t.Errorf("expecting the owner to be deleted, got: %+v (%+v)", exist, err)
+	}
+}
+
+func TestServer_Model(t *testing.T) {
+	s, m := newServerClient(t)
+
+	if err := m.Model.CreateTimeseries([]mnubo.Timeseries{{Key: "speed", Type: mnubo.TimeseriesType{HighLevelType: "DOUBLE"}, EventTypeKeys: []string{"drive"}}}); !mnubo.IsBadRequest(err) {
+		t.Errorf("expecting timeseries of unknown event types to be rejected, got: %+v", err)
+	}
+	if err := m.Model.CreateEventTypes([]mnubo.EventType{{Key: "drive", Origin: "scheduled"}}); err != nil {
+		t.Fatalf("unable to create the event type: %+v", err)
+	}
+	if err := m.Model.CreateTimeseries([]mnubo.Timeseries{{Key: "speed", Type: mnubo.TimeseriesType{HighLevelType: "DOUBLE"}}}); err != nil {
+		t.Fatalf("unable to create the timeseries: %+v", err)
+	}
+	if err := m.Model.AddEventTypeRelation("drive", "speed"); err != nil {
+		t.Errorf("unable to add the relation: %+v", err)
+	}
+	if err := m.Model.UpdateTimeseries("speed", mnubo.Timeseries{DisplayName: "Speed"}); err != nil {
+		t.Errorf("unable to update the timeseries: %+v", err)
+	}
+	if err := m.Model.DeployTimeseriesToProduction("speed"); err != nil {
+		t.Errorf("unable to deploy the timeseries: %+v", err)
+	}
+	if err := m.Model.ApplyTimeseriesDeployCode("speed", mnubo.ChallengeCode{Code: "invalid"}); !mnubo.IsBadRequest(err) {
+		t.Errorf("expecting invalid codes to be rejected, got: %+v", err)
+	}
+
+	var ets []mnubo.EventType
+	if err := m.Model.GetEventTypes(&ets); err != nil || len(ets) != 1 || len(ets[0].TimeseriesKeys) != 1 {
+		t.Errorf("unexpected event types: %+v (%+v)", ets, err)
+	}
+	var dm mnubo.DataModel
+	if err := m.Model.Export(&dm); err != nil || len(dm.EventTypes) != 1 || dm.EventTypes[0].Timeseries[0].DisplayName != "Speed" {
+		t.Errorf("unexpected data model: %+v (%+v)", dm, err)
+	}
+
+	if err := m.Model.RemoveEventTypeRelation("drive", "speed"); err != nil {
+		t.Errorf("unable to remove the relation: %+v", err)
+	}
+	if dm := s.DataModel(); len(dm.Orphans.Timeseries) != 1 {
+		t.Errorf("expecting an orphan timeseries, got: %+v", dm)
+	}
+
+	if err := m.Model.ResetDataModel(); err != nil {
+		t.Errorf("unable to reset the data model: %+v", err)
+	}
+	if dm := s.DataModel(); len(dm.EventTypes) != 0 || len(dm.Orphans.Timeseries) != 0 {
+		t.Errorf("expecting the data model to be reset, got: %+v", dm)
+	}
+}
+
+func TestServer_Search(t *testing.T) {
+	_, m := newServerClient(t)
+	ctx := context.Background()
+
+	events := []serverEvent{
+		{XObject: map[string]string{"x_device_id": "device-1"}, XEventType: "drive", Speed: 10},
+		{XObject: map[string]string{"x_device_id": "device-1"}, XEventType: "drive", Speed: 30},
+		{XObject: map[string]string{"x_device_id": "device-2"}, XEventType: "drive", Speed: 50},
+		{XObject: map[string]string{"x_device_id": "device-2"}, XEventType: "stop", Speed: 0},
+	}
+	if _, err := mnubo.SendEvents(ctx, m.Events, events, mnubo.SendEventsOptions{}); err != nil {
+		t.Fatalf("unable to send events: %+v", err)
+	}
+
+	type speed struct {
+		DeviceID string  `json:"x_device_id"`
+		Count    int     `json:"COUNT(*)"`
+		Average  float64 `json:"avg_speed"`
+	}
+	mql := `{
+		"from": "event",
+		"select": [{"value": "x_device_id"}, {"count": "*"}, {"avg": "speed", "as": "avg_speed"}],
+		"where": {"and": [{"x_event_type": {"eq": "drive"}}, {"speed": {"gt": 5}}]},
+		"groupBy": ["x_device_id"]
+	}`
+	var sr mnubo.SearchResults
+	if err := m.Search.CreateBasicQueryWithString(mql, &sr); err != nil {
+		t.Fatalf("query failed: %+v", err)
+	}
+	if len(sr.Columns) != 3 || sr.Columns[1].Type != "LONG" || len(sr.Rows) != 2 {
+		t.Errorf("unexpected search results: %+v", sr)
+	}
+
+	speeds, err := mnubo.Query[speed](ctx, m.Search, map[string]interface{}{
+		"from":    "event",
+		"select":  []map[string]string{{"value": "x_device_id"}, {"count": "*"}, {"avg": "speed", "as": "avg_speed"}},
+		"where":   map[string]interface{}{"x_event_type": map[string]string{"eq": "drive"}},
+		"groupBy": []string{"x_device_id"},
+	})
+	expected := []speed{{"device-1", 2, 20}, {"device-2", 1, 50}}
+	if err != nil || len(speeds) != 2 || speeds[0] != expected[0] || speeds[1] != expected[1] {
+		t.Errorf("expecting: %+v, got: %+v (%+v)", expected, speeds, err)
+	}
+
+	var qv mnubo.QueryValidation
+	if err := m.Search.ValidateQueryWithString(`{"from": "event", "select": [{"median": "speed"}]}`, &qv); err != nil || qv.IsValid || len(qv.ValidationErrors) != 1 {
+		t.Errorf("expecting the query to be invalid, got: %+v (%+v)", qv, err)
+	}
+	if err := m.Search.CreateBasicQueryWithString(`{"from": "session", "select": [{"count": "*"}]}`, &sr); !mnubo.IsBadRequest(err) {
+		t.Errorf("expecting unknown datasets to be rejected, got: %+v", err)
+	}
+
+	var ds []mnubo.Dataset
+	if err := m.Search.GetDatasets(&ds); err != nil || len(ds) != 3 || ds[0].Key != "event" || len(ds[0].Fields) == 0 {
+		t.Errorf("unexpected datasets: %+v (%+v)", ds, err)
+	}
+}
+
+func TestServer_Authentication(t *testing.T) {
+	s, m := newServerClient(t)
+	var results []mnubo.BatchResult
+
+	static := mnubo.NewClientWithToken(s.IssueToken(), s.URL)
+	if err := static.Objects.Update([]map[string]string{}, &results); err != nil {
+		t.Errorf("expecting the static token to be accepted, got: %+v", err)
+	}
+	if err := m.Objects.Update([]map[string]string{}, &results); err != nil {
+		t.Errorf("expecting the client credentials to be accepted, got: %+v", err)
+	}
+
+	s.RevokeTokens()
+	if err := static.Objects.Update([]map[string]string{}, &results); !mnubo.IsUnauthorized(err) {
+		t.Errorf("expecting the static token to be rejected, got: %+v", err)
+	}
+	if err := m.Objects.Update([]map[string]string{}, &results); err != nil {
+		t.Errorf("expecting the client to get a new token, got: %+v", err)
+	}
+
+	s.ClientSecret = "rotated"
+	if _, err := m.GetAccessToken(); !mnubo.IsUnauthorized(err) {
+		t.Errorf("expecting the credentials to be rejected, got: %+v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package mnubo
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -96,36 +97,54 @@ func NewModel(m *Mnubo) *Model {
 // Export dumps a JSON object representing the current data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#exporting-your-data-model
 func (m *Model) Export(results *DataModel) error {
+	return m.ExportContext(context.Background(), results)
+}
+
+// ExportContext is like Export but uses ctx to cancel the request and its retries.
+func (m *Model) ExportContext(ctx context.Context, results *DataModel) error {
 	cr := ClientRequest{
+		operation:   "Model.Export",
 		method:      "GET",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/export", modelPath),
 	}
 
-	return m.Mnubo.doRequestWithAuthentication(cr, results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // GetTimeseries retrieves the timeseries of the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#getting-all-timeseries
 func (m *Model) GetTimeseries(results *[]Timeseries) error {
+	return m.GetTimeseriesContext(context.Background(), results)
+}
+
+// GetTimeseriesContext is like GetTimeseries but uses ctx to cancel the request and its retries.
+func (m *Model) GetTimeseriesContext(ctx context.Context, results *[]Timeseries) error {
 	cr := ClientRequest{
+		operation:   "Model.GetTimeseries",
 		method:      "GET",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/timeseries", modelPath),
 	}
 
-	return m.Mnubo.doRequestWithAuthentication(cr, results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // CreateObjectAttributes creates new object attribute.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#creating-object-attributes
 func (m *Model) CreateObjectAttributes(oa []ObjectAttribute) error {
+	return m.CreateObjectAttributesContext(context.Background(), oa)
+}
+
+// CreateObjectAttributesContext is like CreateObjectAttributes but uses ctx to cancel the request and its retries.
+func (m *Model) CreateObjectAttributesContext(ctx context.Context, oa []ObjectAttribute) error {
 	bytes, err := json.Marshal(oa)
 
 	if err != nil {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Model.CreateObjectAttributes",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/objectAttributes", modelPath),
@@ -133,18 +152,24 @@ func (m *Model) CreateObjectAttributes(oa []ObjectAttribute) error {
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // UpdateObjectAttribute updates the DisplayName and Description (only those) from an object attribute.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#updating-an-object-attribute
 func (m *Model) UpdateObjectAttribute(key string, oa ObjectAttribute) error {
+	return m.UpdateObjectAttributeContext(context.Background(), key, oa)
+}
+
+// UpdateObjectAttributeContext is like UpdateObjectAttribute but uses ctx to cancel the request and its retries.
+func (m *Model) UpdateObjectAttributeContext(ctx context.Context, key string, oa ObjectAttribute) error {
 	bytes, err := json.Marshal(oa)
 
 	if err != nil {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Model.UpdateObjectAttribute",
 		method:      "PUT",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/objectAttributes/%s", modelPath, key),
@@ -152,67 +177,96 @@ func (m *Model) UpdateObjectAttribute(key string, oa ObjectAttribute) error {
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // GenerateObjectAttributeDeployCode generates a new challenge code before deploying an object attribute to production.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#id10
 func (m *Model) GenerateObjectAttributeDeployCode(key string, results *ChallengeCode) error {
+	return m.GenerateObjectAttributeDeployCodeContext(context.Background(), key, results)
+}
+
+// GenerateObjectAttributeDeployCodeContext is like GenerateObjectAttributeDeployCode but uses ctx to cancel the request and its retries.
+func (m *Model) GenerateObjectAttributeDeployCodeContext(ctx context.Context, key string, results *ChallengeCode) error {
 	cr := ClientRequest{
+		operation:   "Model.GenerateObjectAttributeDeployCode",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/objectAttributes/%s/deploy", modelPath, key),
 	}
 
-	return m.Mnubo.doRequestWithAuthentication(cr, results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // ApplyObjectAttributeDeployCode applies the challenge code to deploy the attribute to production.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#id11
 func (m *Model) ApplyObjectAttributeDeployCode(key string, cc ChallengeCode) error {
+	return m.ApplyObjectAttributeDeployCodeContext(context.Background(), key, cc)
+}
+
+// ApplyObjectAttributeDeployCodeContext is like ApplyObjectAttributeDeployCode but uses ctx to cancel the request and its retries.
+func (m *Model) ApplyObjectAttributeDeployCodeContext(ctx context.Context, key string, cc ChallengeCode) error {
 	cr := ClientRequest{
+		operation:   "Model.ApplyObjectAttributeDeployCode",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/objectAttributes/%s/deploy/%s", modelPath, key, cc.Code),
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // DeployObjectAttributeToProduction deploys an object attribute created in sandbox to production.
 // Making calls to GenerateObjectAttributeDeployCode and ApplyObjectAttributeDeployCode.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#deploying-an-object-attribute-into-production
 func (m *Model) DeployObjectAttributeToProduction(key string) error {
+	return m.DeployObjectAttributeToProductionContext(context.Background(), key)
+}
+
+// DeployObjectAttributeToProductionContext is like DeployObjectAttributeToProduction but uses ctx to cancel the request and its retries.
+func (m *Model) DeployObjectAttributeToProductionContext(ctx context.Context, key string) error {
 	var cc ChallengeCode
-	err := m.GenerateObjectAttributeDeployCode(key, &cc)
+	err := m.GenerateObjectAttributeDeployCodeContext(ctx, key, &cc)
 	if err != nil {
 		return err
 	}
-	return m.ApplyObjectAttributeDeployCode(key, cc)
+	return m.ApplyObjectAttributeDeployCodeContext(ctx, key, cc)
 }
 
 // GetObjectAttributes retrieves the object attributes of the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#getting-all-object-attributes
 func (m *Model) GetObjectAttributes(results *[]ObjectAttribute) error {
+	return m.GetObjectAttributesContext(context.Background(), results)
+}
+
+// GetObjectAttributesContext is like GetObjectAttributes but uses ctx to cancel the request and its retries.
+func (m *Model) GetObjectAttributesContext(ctx context.Context, results *[]ObjectAttribute) error {
 	cr := ClientRequest{
+		operation:   "Model.GetObjectAttributes",
 		method:      "GET",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/objectAttributes", modelPath),
 	}
 
-	return m.Mnubo.doRequestWithAuthentication(cr, results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // CreateTimeseries creates new timeseries to the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#creating-timeseries
 func (m *Model) CreateTimeseries(ts []Timeseries) error {
+	return m.CreateTimeseriesContext(context.Background(), ts)
+}
+
+// CreateTimeseriesContext is like CreateTimeseries but uses ctx to cancel the request and its retries.
+func (m *Model) CreateTimeseriesContext(ctx context.Context, ts []Timeseries) error {
 	bytes, err := json.Marshal(ts)
 
 	if err != nil {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Model.CreateTimeseries",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/timeseries", modelPath),
@@ -220,18 +274,24 @@ func (m *Model) CreateTimeseries(ts []Timeseries) error {
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // UpdateTimeseries updates the DisplayName and Description (only those) from a Timeseries.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#updating-timeseries
 func (m *Model) UpdateTimeseries(key string, ts Timeseries) error {
+	return m.UpdateTimeseriesContext(context.Background(), key, ts)
+}
+
+// UpdateTimeseriesContext is like UpdateTimeseries but uses ctx to cancel the request and its retries.
+func (m *Model) UpdateTimeseriesContext(ctx context.Context, key string, ts Timeseries) error {
 	bytes, err := json.Marshal(ts)
 
 	if err != nil {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Model.UpdateTimeseries",
 		method:      "PUT",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/timeseries/%s", modelPath, key),
@@ -239,55 +299,78 @@ func (m *Model) UpdateTimeseries(key string, ts Timeseries) error {
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // GenerateTimeseriesDeployCode generates a new challenge code before deploying a timeseries to production.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#part-1-getting-code
 func (m *Model) GenerateTimeseriesDeployCode(key string, results *ChallengeCode) error {
+	return m.GenerateTimeseriesDeployCodeContext(context.Background(), key, results)
+}
+
+// GenerateTimeseriesDeployCodeContext is like GenerateTimeseriesDeployCode but uses ctx to cancel the request and its retries.
+func (m *Model) GenerateTimeseriesDeployCodeContext(ctx context.Context, key string, results *ChallengeCode) error {
 	cr := ClientRequest{
+		operation:   "Model.GenerateTimeseriesDeployCode",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/timeseries/%s/deploy", modelPath, key),
 	}
 
-	return m.Mnubo.doRequestWithAuthentication(cr, results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // ApplyTimeseriesDeployCode applies the challenge code to deploy the timeseries to production.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#part-2-challenging-code
 func (m *Model) ApplyTimeseriesDeployCode(key string, cc ChallengeCode) error {
+	return m.ApplyTimeseriesDeployCodeContext(context.Background(), key, cc)
+}
+
+// ApplyTimeseriesDeployCodeContext is like ApplyTimeseriesDeployCode but uses ctx to cancel the request and its retries.
+func (m *Model) ApplyTimeseriesDeployCodeContext(ctx context.Context, key string, cc ChallengeCode) error {
 	cr := ClientRequest{
+		operation:   "Model.ApplyTimeseriesDeployCode",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/timeseries/%s/deploy/%s", modelPath, key, cc.Code),
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // DeployTimeseriesToProduction deploys a timeseries created in sandbox to production.
 // Making calls to GenerateTimeseriesDeployCode and ApplyTimeseriesDeployCode.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#deploying-a-timeseries-into-production
 func (m *Model) DeployTimeseriesToProduction(key string) error {
+	return m.DeployTimeseriesToProductionContext(context.Background(), key)
+}
+
+// DeployTimeseriesToProductionContext is like DeployTimeseriesToProduction but uses ctx to cancel the request and its retries.
+func (m *Model) DeployTimeseriesToProductionContext(ctx context.Context, key string) error {
 	var cc ChallengeCode
-	err := m.GenerateTimeseriesDeployCode(key, &cc)
+	err := m.GenerateTimeseriesDeployCodeContext(ctx, key, &cc)
 	if err != nil {
 		return err
 	}
-	return m.ApplyTimeseriesDeployCode(key, cc)
+	return m.ApplyTimeseriesDeployCodeContext(ctx, key, cc)
 }
 
 // CreateOwnerAttributes creates new owner attribute.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#creating-object-attributes
 func (m *Model) CreateOwnerAttributes(oa []OwnerAttribute) error {
+	return m.CreateOwnerAttributesContext(context.Background(), oa)
+}
+
+// CreateOwnerAttributesContext is like CreateOwnerAttributes but uses ctx to cancel the request and its retries.
+func (m *Model) CreateOwnerAttributesContext(ctx context.Context, oa []OwnerAttribute) error {
 	bytes, err := json.Marshal(oa)
 
 	if err != nil {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Model.CreateOwnerAttributes",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/ownerAttributes", modelPath),
@@ -295,18 +378,24 @@ func (m *Model) CreateOwnerAttributes(oa []OwnerAttribute) error {
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // UpdateOwnerAttribute updates the DisplayName and Description (only those) from an owner attribute.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#updating-an-object-attribute
 func (m *Model) UpdateOwnerAttribute(key string, oa OwnerAttribute) error {
+	return m.UpdateOwnerAttributeContext(context.Background(), key, oa)
+}
+
+// UpdateOwnerAttributeContext is like UpdateOwnerAttribute but uses ctx to cancel the request and its retries.
+func (m *Model) UpdateOwnerAttributeContext(ctx context.Context, key string, oa OwnerAttribute) error {
 	bytes, err := json.Marshal(oa)
 
 	if err != nil {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Model.UpdateOwnerAttribute",
 		method:      "PUT",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/ownerAttributes/%s", modelPath, key),
@@ -314,79 +403,114 @@ func (m *Model) UpdateOwnerAttribute(key string, oa OwnerAttribute) error {
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // GenerateOwnerAttributeDeployCode generates a new challenge code before deploying an owner attribute to production.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#id10
 func (m *Model) GenerateOwnerAttributeDeployCode(key string, results *ChallengeCode) error {
+	return m.GenerateOwnerAttributeDeployCodeContext(context.Background(), key, results)
+}
+
+// GenerateOwnerAttributeDeployCodeContext is like GenerateOwnerAttributeDeployCode but uses ctx to cancel the request and its retries.
+func (m *Model) GenerateOwnerAttributeDeployCodeContext(ctx context.Context, key string, results *ChallengeCode) error {
 	cr := ClientRequest{
+		operation:   "Model.GenerateOwnerAttributeDeployCode",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/ownerAttributes/%s/deploy", modelPath, key),
 	}
 
-	return m.Mnubo.doRequestWithAuthentication(cr, results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // ApplyOwnerAttributeDeployCode applies the challenge code to deploy the attribute to production.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#id11
 func (m *Model) ApplyOwnerAttributeDeployCode(key string, cc ChallengeCode) error {
+	return m.ApplyOwnerAttributeDeployCodeContext(context.Background(), key, cc)
+}
+
+// ApplyOwnerAttributeDeployCodeContext is like ApplyOwnerAttributeDeployCode but uses ctx to cancel the request and its retries.
+func (m *Model) ApplyOwnerAttributeDeployCodeContext(ctx context.Context, key string, cc ChallengeCode) error {
 	cr := ClientRequest{
+		operation:   "Model.ApplyOwnerAttributeDeployCode",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/ownerAttributes/%s/deploy/%s", modelPath, key, cc.Code),
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // DeployOwnerAttributeToProduction deploys an owner attribute created in sandbox to production.
 // Making calls to GenerateOwnerAttributeDeployCode and ApplyOwnerAttributeDeployCode.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#deploying-an-object-attribute-into-production
 func (m *Model) DeployOwnerAttributeToProduction(key string) error {
+	return m.DeployOwnerAttributeToProductionContext(context.Background(), key)
+}
+
+// DeployOwnerAttributeToProductionContext is like DeployOwnerAttributeToProduction but uses ctx to cancel the request and its retries.
+func (m *Model) DeployOwnerAttributeToProductionContext(ctx context.Context, key string) error {
 	var cc ChallengeCode
-	err := m.GenerateOwnerAttributeDeployCode(key, &cc)
+	err := m.GenerateOwnerAttributeDeployCodeContext(ctx, key, &cc)
 	if err != nil {
 		return err
 	}
-	return m.ApplyOwnerAttributeDeployCode(key, cc)
+	return m.ApplyOwnerAttributeDeployCodeContext(ctx, key, cc)
 }
 
 // GetOwnerAttributes retrieves the owner attributes of the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#getting-all-owner-attributes
 func (m *Model) GetOwnerAttributes(results *[]OwnerAttribute) error {
+	return m.GetOwnerAttributesContext(context.Background(), results)
+}
+
+// GetOwnerAttributesContext is like GetOwnerAttributes but uses ctx to cancel the request and its retries.
+func (m *Model) GetOwnerAttributesContext(ctx context.Context, results *[]OwnerAttribute) error {
 	cr := ClientRequest{
+		operation:   "Model.GetOwnerAttributes",
 		method:      "GET",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/ownerAttributes", modelPath),
 	}
 
-	return m.Mnubo.doRequestWithAuthentication(cr, results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // GetEventTypes retrieves the event types of the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#getting-all-event-types
 func (m *Model) GetEventTypes(results *[]EventType) error {
+	return m.GetEventTypesContext(context.Background(), results)
+}
+
+// GetEventTypesContext is like GetEventTypes but uses ctx to cancel the request and its retries.
+func (m *Model) GetEventTypesContext(ctx context.Context, results *[]EventType) error {
 	cr := ClientRequest{
+		operation:   "Model.GetEventTypes",
 		method:      "GET",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/eventTypes", modelPath),
 	}
 
-	return m.Mnubo.doRequestWithAuthentication(cr, results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // CreateEventTypes creates an array of event types in the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#creating-event-types
 func (m *Model) CreateEventTypes(et []EventType) error {
+	return m.CreateEventTypesContext(context.Background(), et)
+}
+
+// CreateEventTypesContext is like CreateEventTypes but uses ctx to cancel the request and its retries.
+func (m *Model) CreateEventTypesContext(ctx context.Context, et []EventType) error {
 	bytes, err := json.Marshal(et)
 
 	if err != nil {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Model.CreateEventTypes",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/eventTypes", modelPath),
@@ -394,18 +518,24 @@ func (m *Model) CreateEventTypes(et []EventType) error {
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // UpdateEventType updates an event type in the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#updating-an-event-type
 func (m *Model) UpdateEventType(key string, et EventType) error {
+	return m.UpdateEventTypeContext(context.Background(), key, et)
+}
+
+// UpdateEventTypeContext is like UpdateEventType but uses ctx to cancel the request and its retries.
+func (m *Model) UpdateEventTypeContext(ctx context.Context, key string, et EventType) error {
 	bytes, err := json.Marshal(et)
 
 	if err != nil {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Model.UpdateEventType",
 		method:      "PUT",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/eventTypes/%s", modelPath, key),
@@ -413,67 +543,97 @@ func (m *Model) UpdateEventType(key string, et EventType) error {
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // DeleteEventType deletes an event type from the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#deleting-an-event-type
 func (m *Model) DeleteEventType(key string) error {
+	return m.DeleteEventTypeContext(context.Background(), key)
+}
+
+// DeleteEventTypeContext is like DeleteEventType but uses ctx to cancel the request and its retries.
+func (m *Model) DeleteEventTypeContext(ctx context.Context, key string) error {
 	cr := ClientRequest{
+		operation:   "Model.DeleteEventType",
 		method:      "DELETE",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/eventTypes/%s", modelPath, key),
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // Add a relation to a timeseries.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#linking-a-timeseries-to-an-event-type
 func (m *Model) AddEventTypeRelation(typeKey string, entityKey string) error {
+	return m.AddEventTypeRelationContext(context.Background(), typeKey, entityKey)
+}
+
+// AddEventTypeRelationContext is like AddEventTypeRelation but uses ctx to cancel the request and its retries.
+func (m *Model) AddEventTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error {
 	cr := ClientRequest{
-		method: "POST",
-		path:   fmt.Sprintf("%s/eventTypes/%s/timeseries/%s", modelPath, typeKey, entityKey),
+		operation: "Model.AddEventTypeRelation",
+		method:    "POST",
+		path:      fmt.Sprintf("%s/eventTypes/%s/timeseries/%s", modelPath, typeKey, entityKey),
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // Remove a relation to a timeseries.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#deleting-the-link-between-a-timeseries-and-an-event-type
 func (m *Model) RemoveEventTypeRelation(typeKey string, entityKey string) error {
+	return m.RemoveEventTypeRelationContext(context.Background(), typeKey, entityKey)
+}
+
+// RemoveEventTypeRelationContext is like RemoveEventTypeRelation but uses ctx to cancel the request and its retries.
+func (m *Model) RemoveEventTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error {
 	cr := ClientRequest{
-		method: "DELETE",
-		path:   fmt.Sprintf("%s/eventTypes/%s/timeseries/%s", modelPath, typeKey, entityKey),
+		operation: "Model.RemoveEventTypeRelation",
+		method:    "DELETE",
+		path:      fmt.Sprintf("%s/eventTypes/%s/timeseries/%s", modelPath, typeKey, entityKey),
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // GetObjectTypes retrieves the object types of the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#getting-all-event-types
 func (m *Model) GetObjectTypes(results *[]ObjectType) error {
+	return m.GetObjectTypesContext(context.Background(), results)
+}
+
+// GetObjectTypesContext is like GetObjectTypes but uses ctx to cancel the request and its retries.
+func (m *Model) GetObjectTypesContext(ctx context.Context, results *[]ObjectType) error {
 	cr := ClientRequest{
+		operation:   "Model.GetObjectTypes",
 		method:      "GET",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/objectTypes", modelPath),
 	}
 
-	return m.Mnubo.doRequestWithAuthentication(cr, results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // CreateObjectTypes creates an array of object types in the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#creating-object-types
 func (m *Model) CreateObjectTypes(ot []ObjectType) error {
+	return m.CreateObjectTypesContext(context.Background(), ot)
+}
+
+// CreateObjectTypesContext is like CreateObjectTypes but uses ctx to cancel the request and its retries.
+func (m *Model) CreateObjectTypesContext(ctx context.Context, ot []ObjectType) error {
 	bytes, err := json.Marshal(ot)
 
 	if err != nil {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Model.CreateObjectTypes",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/objectTypes", modelPath),
@@ -481,18 +641,24 @@ func (m *Model) CreateObjectTypes(ot []ObjectType) error {
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // UpdateObjectType updates an object type in the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#updating-an-object-type
 func (m *Model) UpdateObjectType(key string, ot ObjectType) error {
+	return m.UpdateObjectTypeContext(context.Background(), key, ot)
+}
+
+// UpdateObjectTypeContext is like UpdateObjectType but uses ctx to cancel the request and its retries.
+func (m *Model) UpdateObjectTypeContext(ctx context.Context, key string, ot ObjectType) error {
 	bytes, err := json.Marshal(ot)
 
 	if err != nil {
 		return err
 	}
 	cr := ClientRequest{
+		operation:   "Model.UpdateObjectType",
 		method:      "PUT",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/objectTypes/%s", modelPath, key),
@@ -500,79 +666,114 @@ func (m *Model) UpdateObjectType(key string, ot ObjectType) error {
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // DeleteObjectType deletes an object type from the data model.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#deleting-an-object-type
 func (m *Model) DeleteObjectType(key string) error {
+	return m.DeleteObjectTypeContext(context.Background(), key)
+}
+
+// DeleteObjectTypeContext is like DeleteObjectType but uses ctx to cancel the request and its retries.
+func (m *Model) DeleteObjectTypeContext(ctx context.Context, key string) error {
 	cr := ClientRequest{
+		operation:   "Model.DeleteObjectType",
 		method:      "DELETE",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/objectTypes/%s", modelPath, key),
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // Add a relation to an object attribute.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#linking-an-attribute-to-an-object-type
 func (m *Model) AddObjectTypeRelation(typeKey string, entityKey string) error {
+	return m.AddObjectTypeRelationContext(context.Background(), typeKey, entityKey)
+}
+
+// AddObjectTypeRelationContext is like AddObjectTypeRelation but uses ctx to cancel the request and its retries.
+func (m *Model) AddObjectTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error {
 	cr := ClientRequest{
-		method: "POST",
-		path:   fmt.Sprintf("%s/objectTypes/%s/objectAttributes/%s", modelPath, typeKey, entityKey),
+		operation: "Model.AddObjectTypeRelation",
+		method:    "POST",
+		path:      fmt.Sprintf("%s/objectTypes/%s/objectAttributes/%s", modelPath, typeKey, entityKey),
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // Remove a relation to an object attribute.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#deleting-the-link-between-an-attribute-and-an-object-type
 func (m *Model) RemoveObjectTypeRelation(typeKey string, entityKey string) error {
+	return m.RemoveObjectTypeRelationContext(context.Background(), typeKey, entityKey)
+}
+
+// RemoveObjectTypeRelationContext is like RemoveObjectTypeRelation but uses ctx to cancel the request and its retries.
+func (m *Model) RemoveObjectTypeRelationContext(ctx context.Context, typeKey string, entityKey string) error {
 	cr := ClientRequest{
-		method: "DELETE",
-		path:   fmt.Sprintf("%s/objectTypes/%s/objectAttributes/%s", modelPath, typeKey, entityKey),
+		operation: "Model.RemoveObjectTypeRelation",
+		method:    "DELETE",
+		path:      fmt.Sprintf("%s/objectTypes/%s/objectAttributes/%s", modelPath, typeKey, entityKey),
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // GenerateResetCode generates a new code that must be used in order to reset a data model
 // in sandbox.
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#resetting-your-sandbox-data-model
 func (m *Model) GenerateResetCode(results *ChallengeCode) error {
+	return m.GenerateResetCodeContext(context.Background(), results)
+}
+
+// GenerateResetCodeContext is like GenerateResetCode but uses ctx to cancel the request and its retries.
+func (m *Model) GenerateResetCodeContext(ctx context.Context, results *ChallengeCode) error {
 	cr := ClientRequest{
+		operation:   "Model.GenerateResetCode",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/reset", modelPath),
 	}
 
-	return m.Mnubo.doRequestWithAuthentication(cr, results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // ApplyResetCode sends the reset code for sandbox reset (if the code is valid).
 // See: https://smartobjects.mnubo.com/documentation/api_modeler.html#part-2-using-the-code
 func (m *Model) ApplyResetCode(cc ChallengeCode) error {
+	return m.ApplyResetCodeContext(context.Background(), cc)
+}
+
+// ApplyResetCodeContext is like ApplyResetCode but uses ctx to cancel the request and its retries.
+func (m *Model) ApplyResetCodeContext(ctx context.Context, cc ChallengeCode) error {
 	cr := ClientRequest{
+		operation:   "Model.ApplyResetCode",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/reset/%s", modelPath, cc.Code),
 	}
 
 	var results interface{}
-	return m.Mnubo.doRequestWithAuthentication(cr, &results)
+	return m.Mnubo.doRequestWithAuthentication(ctx, cr, &results)
 }
 
 // ResetDataModel performs both GenerateResetCode and ApplyResetCode at the same time
 // for convenience.
 func (m *Model) ResetDataModel() error {
+	return m.ResetDataModelContext(context.Background())
+}
+
+// ResetDataModelContext is like ResetDataModel but uses ctx to cancel the request and its retries.
+func (m *Model) ResetDataModelContext(ctx context.Context) error {
 	var cc ChallengeCode
-	err := m.GenerateResetCode(&cc)
+	err := m.GenerateResetCodeContext(ctx, &cc)
 	if err != nil {
 		return err
 	}
-	return m.ApplyResetCode(cc)
+	return m.ApplyResetCodeContext(ctx, cc)
 }
@@ -0,0 +1,131 @@
+package mnubo
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Observer is notified of the activity of a client, to trace it or to export metrics.
+// The mnubootel and mnuboprom packages provide OpenTelemetry and Prometheus observers.
+// Implementations must be safe for concurrent use.
+type Observer interface {
+	// StartOperation is called when a client call starts, like Events.Send. The returned context is used to
+	// send the HTTP requests of the call, and the returned OperationObserver is notified of their outcome.
+	StartOperation(ctx context.Context, info OperationInfo) (context.Context, OperationObserver)
+	// ObserveTokenRefresh is called after requesting an access token with the client id and secret.
+	ObserveTokenRefresh(err error)
+}
+
+// OperationInfo describes a client call.
+type OperationInfo struct {
+	// Name identifies the call, like Events.Send, or is the method and path of its request.
+	Name   string
+	Method string
+	Path   string
+	// Events is the number of events sent by the call, 0 if it doesn't send events.
+	Events int
+}
+
+// OperationObserver is notified of the progress of a client call.
+type OperationObserver interface {
+	// ObservePayload is called with the size of the request payload, before and after compression.
+	ObservePayload(size int, sentSize int)
+	// StartAttempt is called before sending each HTTP request of the call, attempt starting at 1.
+	// attempt starts again at 1 when the request is sent once more with a new access token,
+	// after the platform rejected the previous one, so attempts > 1 are retries of the RetryPolicy.
+	// The returned context is used to send the request, and the returned function is called with its outcome.
+	StartAttempt(ctx context.Context, req *http.Request, attempt int) (context.Context, func(*http.Response, error))
+	// End is called when the call returns, with the number of HTTP requests sent, retries included.
+	End(attempts int, err error)
+}
+
+type operationContextKey struct{}
+
+// operation is a logical client call, like Events.Send, which can send several HTTP requests.
+type operation struct {
+	observers []OperationObserver
+	log       *requestLogger
+	name      string
+	attempts  int
+	// payload is the uncompressed payload of the request, once built.
+	payload []byte
+}
+
+// startOperation notifies the observers and the logger of the client that a call starts, and makes
+// the operation available to its HTTP attempts through ctx.
+func (m *Mnubo) startOperation(ctx context.Context, cr *ClientRequest) (context.Context, *operation) {
+	info := OperationInfo{
+		Name:   cr.operation,
+		Method: cr.method,
+		Path:   cr.path,
+		Events: cr.events,
+	}
+	if info.Name == "" {
+		info.Name = cr.method + " " + cr.path
+	}
+
+	op := &operation{
+		log:  m.newRequestLogger(),
+		name: info.Name,
+	}
+	for _, o := range m.Observers {
+		var observer OperationObserver
+		ctx, observer = o.StartOperation(ctx, info)
+		op.observers = append(op.observers, observer)
+	}
+	return context.WithValue(ctx, operationContextKey{}, op), op
+}
+
+// observeTokenRefresh notifies the observers of the client of a token request.
+func (m *Mnubo) observeTokenRefresh(err error) {
+	for _, o := range m.Observers {
+		o.ObserveTokenRefresh(err)
+	}
+}
+
+// operationFromContext returns the operation started in ctx, or nil.
+func operationFromContext(ctx context.Context) *operation {
+	op, _ := ctx.Value(operationContextKey{}).(*operation)
+	return op
+}
+
+// end records the outcome of the client call.
+func (op *operation) end(err error) {
+	for _, o := range op.observers {
+		o.End(op.attempts, err)
+	}
+}
+
+// recordPayload records a request payload and its size after compression.
+func (op *operation) recordPayload(payload []byte, sentSize int) {
+	if op == nil {
+		return
+	}
+	op.payload = payload
+	for _, o := range op.observers {
+		o.ObservePayload(len(payload), sentSize)
+	}
+}
+
+// startAttempt notifies the observers that an HTTP request is sent for the operation.
+// It returns the context to send the request with, and a function recording its outcome.
+func (op *operation) startAttempt(ctx context.Context, req *http.Request, attempt int) (context.Context, func(*http.Response, error)) {
+	if op == nil {
+		return ctx, func(*http.Response, error) {}
+	}
+	op.attempts++
+	start := time.Now()
+
+	ends := make([]func(*http.Response, error), len(op.observers))
+	for i, o := range op.observers {
+		ctx, ends[i] = o.StartAttempt(ctx, req, attempt)
+	}
+
+	return ctx, func(res *http.Response, err error) {
+		op.log.logAttempt(ctx, op, req, attempt, start, res, err)
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](res, err)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package mnubo
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// ErrInvalidConfig is wrapped by the errors of New, NewFromEnv and NewFromConfigFile for missing or malformed settings.
+var ErrInvalidConfig = errors.New("invalid SmartObjects client configuration")
+
+// Option configures a client created with New.
+type Option func(m *Mnubo) error
+
+// New creates a new Mnubo structure configured by opts.
+// A host and credentials are required, see WithHost, WithClientCredentials, WithToken and WithTokenSource.
+// The client is fully configured when it is returned, and can be shared by several goroutines.
+func New(opts ...Option) (*Mnubo, error) {
+	m := &Mnubo{}
+	m.initClient()
+
+	var errs []error
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, m.validate()...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return m, nil
+}
+
+// invalidConfig returns an error wrapping ErrInvalidConfig.
+func invalidConfig(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, a...))
+}
+
+// validate returns the errors of a client configuration, and normalizes its host.
+func (m *Mnubo) validate() []error {
+	var errs []error
+
+	if host, err := NormalizeHost(m.Host); err != nil {
+		errs = append(errs, err)
+	} else {
+		m.Host = host
+	}
+
+	switch {
+	case m.ClientId != "" && m.ClientSecret == "":
+		errs = append(errs, invalidConfig("client secret is required with a client id"))
+	case m.ClientId == "" && m.ClientSecret != "":
+		errs = append(errs, invalidConfig("client id is required with a client secret"))
+	case m.ClientId == "" && m.ClientToken == "" && m.tokenSource == TokenSource(m.tokens):
+		errs = append(errs, invalidConfig("credentials are required: client id and secret, token or token source"))
+	}
+	// The static token would silently win over the other credentials.
+	credentials := 0
+	for _, set := range []bool{m.ClientId != "" || m.ClientSecret != "", m.ClientToken != "", m.tokenSource != TokenSource(m.tokens)} {
+		if set {
+			credentials++
+		}
+	}
+	if credentials > 1 {
+		errs = append(errs, invalidConfig("only one of client id and secret, token or token source can be set"))
+	}
+
+	if m.Timeout < 0 {
+		errs = append(errs, invalidConfig("timeout must not be negative"))
+	}
+	if m.ExponentialBackoff.MaxElapsedTime < 0 {
+		errs = append(errs, invalidConfig("max elapsed time must not be negative"))
+	}
+	if m.RetryPolicy.MaxAttempts < 0 {
+		errs = append(errs, invalidConfig("max attempts must not be negative"))
+	}
+	if m.RetryPolicy.Jitter < 0 || m.RetryPolicy.Jitter > 1 {
+		errs = append(errs, invalidConfig("jitter must be between 0 and 1"))
+	}
+	if err := m.Compression.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, m.RateLimit.validate()...)
+	errs = append(errs, m.CircuitBreaker.validate()...)
+	return errs
+}
+
+// WithHost sets the URL of the SmartObjects platform, like https://rest.sandbox.mnubo.com.
+// See WithEnvironment for the SmartObjects environments.
+func WithHost(host string) Option {
+	return func(m *Mnubo) error {
+		m.Host = host
+		return nil
+	}
+}
+
+// WithClientCredentials authenticates with access tokens obtained from a client id and secret.
+// It can't be combined with WithToken or WithTokenSource.
+func WithClientCredentials(id string, secret string) Option {
+	return func(m *Mnubo) error {
+		m.ClientId = id
+		m.ClientSecret = secret
+		return nil
+	}
+}
+
+// WithScope sets the scope of the access tokens obtained with client credentials, ALL by default.
+func WithScope(scope string) Option {
+	return func(m *Mnubo) error {
+		if scope == "" {
+			return invalidConfig("scope must not be empty")
+		}
+		m.Scopes.Default = scope
+		return nil
+	}
+}
+
+// WithScopes sets the scopes of the access tokens obtained with client credentials, per group of endpoints.
+func WithScopes(scopes Scopes) Option {
+	return func(m *Mnubo) error {
+		m.Scopes = scopes
+		return nil
+	}
+}
+
+// WithTokenCache persists the tokens obtained with client credentials in cache, to share them between processes.
+func WithTokenCache(cache *FileTokenCache) Option {
+	return func(m *Mnubo) error {
+		if cache == nil || cache.Dir == "" {
+			return invalidConfig("token cache directory is required")
+		}
+		m.TokenCache = cache
+		return nil
+	}
+}
+
+// WithToken authenticates with a static token.
+// It can't be combined with WithClientCredentials or WithTokenSource.
+func WithToken(token string) Option {
+	return func(m *Mnubo) error {
+		m.ClientToken = token
+		return nil
+	}
+}
+
+// WithTokenSource authenticates with access tokens provided by ts.
+// It can't be combined with WithClientCredentials or WithToken.
+func WithTokenSource(ts TokenSource) Option {
+	return func(m *Mnubo) error {
+		if ts == nil {
+			return invalidConfig("token source must not be nil")
+		}
+		bindTokenSource(ts, m)
+		m.tokenSource = ts
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of each HTTP request sent to SmartObjects.
+func WithTimeout(timeout time.Duration) Option {
+	return func(m *Mnubo) error {
+		m.Timeout = timeout
+		return nil
+	}
+}
+
+// WithCompression compresses requests and / or responses, see CompressionConfig.
+func WithCompression(config CompressionConfig) Option {
+	return func(m *Mnubo) error {
+		m.Compression = config
+		return nil
+	}
+}
+
+// WithExponentialBackoff replaces the configuration of the exponential backoff.
+func WithExponentialBackoff(config ExponentialBackoffConfig) Option {
+	return func(m *Mnubo) error {
+		m.ExponentialBackoff = config
+		return nil
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(m *Mnubo) error {
+		m.RetryPolicy = policy
+		return nil
+	}
+}
+
+// WithHTTPClient sends requests with client, whose Timeout is replaced by the client timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(m *Mnubo) error {
+		if client == nil {
+			return invalidConfig("HTTP client must not be nil")
+		}
+		m.HttpClient = client
+		return nil
+	}
+}
+
+// WithTransport sends requests with rt.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(m *Mnubo) error {
+		if rt == nil {
+			return invalidConfig("transport must not be nil")
+		}
+		m.HttpClient = &http.Client{Transport: rt}
+		return nil
+	}
+}
+
+// WithMiddlewares adds middlewares called around each request, the first one being the outermost.
+func WithMiddlewares(middlewares ...Middleware) Option {
+	return func(m *Mnubo) error {
+		m.Middlewares = append(m.Middlewares, middlewares...)
+		return nil
+	}
+}
+
+// WithObservers notifies observers of the client activity, see Observer.
+func WithObservers(observers ...Observer) Option {
+	return func(m *Mnubo) error {
+		m.Observers = append(m.Observers, observers...)
+		return nil
+	}
+}
+
+// WithLogger logs requests with logger, according to config.
+func WithLogger(logger *slog.Logger, config LogConfig) Option {
+	return func(m *Mnubo) error {
+		m.Logger = logger
+		m.LogConfig = config
+		return nil
+	}
+}
+
+// WithRateLimit limits the rate of requests.
+func WithRateLimit(config RateLimitConfig) Option {
+	return func(m *Mnubo) error {
+		m.RateLimit = config
+		return nil
+	}
+}
+
+// WithCircuitBreaker enables a circuit breaker, starting from DefaultCircuitBreakerConfig is recommended.
+func WithCircuitBreaker(config CircuitBreakerConfig) Option {
+	return func(m *Mnubo) error {
+		m.CircuitBreaker = config
+		return nil
+	}
+}
@@ -0,0 +1,99 @@
+package mnubo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	var received *http.Request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/oauth/token" {
+			r.ParseForm()
+			w.Write([]byte(`{"access_token":"TOKEN","token_type":"Bearer","expires_in":3600000,"scope":"` + r.Form.Get("scope") + `"}`))
+			return
+		}
+		received = r
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	m, err := New(
+		WithHost(ts.URL),
+		WithClientCredentials("CLIENT_ID", "CLIENT_SECRET"),
+		WithScope("READ"),
+		WithTimeout(time.Second),
+		WithCompression(CompressionConfig{Response: true}),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2}),
+		WithMiddlewares(HeaderMiddleware(http.Header{"X-Tenant": {"tenant-1"}})),
+	)
+
+	if err != nil {
+		t.Fatalf("unable to create client: %+v", err)
+	}
+	if m.Timeout != time.Second || !m.Compression.Response || m.RetryPolicy.MaxAttempts != 2 {
+		t.Errorf("expecting options to be applied, got: %+v", m)
+	}
+	var results []Dataset
+	if err := m.Search.GetDatasets(&results); err != nil {
+		t.Fatalf("client call failed: %+v", err)
+	}
+	if at := m.CachedAccessToken(""); at.Scope != "READ" {
+		t.Errorf("expecting token with scope READ, got: %s", at.Scope)
+	}
+	if received.Header.Get("X-Tenant") != "tenant-1" || received.Header.Get("Accept-Encoding") != "gzip" {
+		t.Errorf("expecting middleware and compression headers, got: %+v", received.Header)
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	cases := []struct {
+		Options  []Option
+		Expected []string
+	}{
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN")}, nil},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithClientCredentials("id", "secret")}, nil},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithTokenSource(StaticTokenSource("TOKEN"))}, nil},
+		{[]Option{WithToken("TOKEN")}, []string{"host is required"}},
+		{[]Option{WithHost("ftp://rest.sandbox.mnubo.com"), WithToken("TOKEN")}, []string{"is not an http(s) URL"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com")}, []string{"credentials are required"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithClientCredentials("id", "")}, []string{"client secret is required"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithClientCredentials("", "secret")}, []string{"client id is required"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithClientCredentials("id", "secret"), WithToken("TOKEN")}, []string{"only one of client id and secret, token or token source"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN"), WithTokenSource(StaticTokenSource("TOKEN"))}, []string{"only one of client id and secret, token or token source"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN"), WithTokenSource(nil)}, []string{"token source must not be nil"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN"), WithScope("")}, []string{"scope must not be empty"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN"), WithHTTPClient(nil), WithTransport(nil)}, []string{"HTTP client must not be nil", "transport must not be nil"}},
+		{[]Option{WithToken("TOKEN"), WithTimeout(-time.Second), WithRetryPolicy(RetryPolicy{MaxAttempts: -1, Jitter: 2})}, []string{"host is required", "timeout must not be negative", "max attempts must not be negative", "jitter must be between 0 and 1"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN"), WithRateLimit(RateLimitConfig{Global: RateLimit{Rate: 10}})}, nil},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN"), WithRateLimit(RateLimitConfig{Search: RateLimit{Rate: -1}, Decrease: 1, Recovery: -1})}, []string{"search rate limit and burst must not be negative", "decrease must be between 0 and 1", "recovery must not be negative"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN"), WithRateLimit(RateLimitConfig{Decrease: 0.5, Minimum: 2, Recovery: 0.1})}, []string{"minimum must be between 0, excluded, and 1", "recovery interval must be positive"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN"), WithCircuitBreaker(CircuitBreakerConfig{FailureRate: 2, MinRequests: 1, Window: time.Second, OpenTimeout: time.Second})}, []string{"failure rate must be between 0 and 1"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN"), WithCircuitBreaker(CircuitBreakerConfig{FailureRate: 0.5})}, []string{"minimum requests must be positive", "window must be positive", "open timeout must be positive"}},
+		{[]Option{WithHost("https://rest.sandbox.mnubo.com"), WithToken("TOKEN"), WithCircuitBreaker(CircuitBreakerConfig{MinRequests: -1})}, nil},
+	}
+	for i, c := range cases {
+		m, err := New(c.Options...)
+
+		if c.Expected == nil {
+			if err != nil || m == nil {
+				t.Errorf("%d, expecting a client, got: %+v", i, err)
+			}
+			continue
+		}
+		if m != nil || !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("%d, expecting ErrInvalidConfig, got: %+v", i, err)
+			continue
+		}
+		for _, e := range c.Expected {
+			if !strings.Contains(err.Error(), e) {
+				t.Errorf("%d, expecting error: %s, got: %s", i, e, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package mnubo
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRateLimitDecrease         = 0.5
+	DefaultRateLimitMinimum          = 0.1
+	DefaultRateLimitRecovery         = 0.1
+	DefaultRateLimitRecoveryInterval = time.Second * 5
+)
+
+// RateLimit is the rate of a token bucket, in requests per second.
+type RateLimit struct {
+	// Rate is the number of requests per second, there is no limit if Rate is 0.
+	Rate float64
+	// Burst is the number of requests which can be sent at once, it defaults to the rate rounded up.
+	Burst int
+}
+
+// RateLimitConfig limits the requests sent by a client, so the goroutines sharing it stay below the platform quotas.
+// Each request takes a token from the Global bucket and from the bucket of its endpoint group.
+// It must be configured before the first request.
+type RateLimitConfig struct {
+	Global RateLimit
+	// Ingestion limits the Events, Objects and Owners requests.
+	Ingestion RateLimit
+	// Search limits the Search requests.
+	Search RateLimit
+	// Modeler limits the Model requests.
+	Modeler RateLimit
+	// Decrease multiplies the rates of the buckets of a request getting a 429 or 503 response.
+	// Rates are not adapted if it is 0.
+	Decrease float64
+	// Minimum is the lowest rate the buckets can be decreased to, as a fraction of their Rate.
+	Minimum float64
+	// Recovery is the fraction of their Rate added to decreased buckets after each RecoveryInterval without throttling.
+	Recovery         float64
+	RecoveryInterval time.Duration
+}
+
+// DefaultRateLimitConfig does not limit requests, but adapts the rates which get configured.
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Decrease:         DefaultRateLimitDecrease,
+		Minimum:          DefaultRateLimitMinimum,
+		Recovery:         DefaultRateLimitRecovery,
+		RecoveryInterval: DefaultRateLimitRecoveryInterval,
+	}
+}
+
+// validate returns the errors of a rate limit configuration.
+func (c *RateLimitConfig) validate() []error {
+	var errs []error
+	for _, l := range []struct {
+		name  string
+		limit RateLimit
+	}{{"global", c.Global}, {"ingestion", c.Ingestion}, {"search", c.Search}, {"modeler", c.Modeler}} {
+		if l.limit.Rate < 0 || l.limit.Burst < 0 {
+			errs = append(errs, invalidConfig("%s rate limit and burst must not be negative", l.name))
+		}
+	}
+	if c.Decrease < 0 || c.Decrease >= 1 {
+		errs = append(errs, invalidConfig("rate limit decrease must be between 0 and 1, excluded"))
+	}
+	// Minimum and recovery only apply to decreased rates.
+	if c.Decrease > 0 && (c.Minimum <= 0 || c.Minimum > 1) {
+		errs = append(errs, invalidConfig("rate limit minimum must be between 0, excluded, and 1"))
+	}
+	if c.Recovery < 0 {
+		errs = append(errs, invalidConfig("rate limit recovery must not be negative"))
+	}
+	if c.Recovery > 0 && c.RecoveryInterval <= 0 {
+		errs = append(errs, invalidConfig("rate limit recovery interval must be positive"))
+	}
+	return errs
+}
+
+// rateLimiter holds the token buckets of a client.
+type rateLimiter struct {
+	global    *tokenBucket
+	ingestion *tokenBucket
+	search    *tokenBucket
+	modeler   *tokenBucket
+}
+
+// newRateLimiter creates the buckets of config, nil buckets don't limit requests.
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		global:    newTokenBucket(config.Global, config),
+		ingestion: newTokenBucket(config.Ingestion, config),
+		search:    newTokenBucket(config.Search, config),
+		modeler:   newTokenBucket(config.Modeler, config),
+	}
+}
+
+// rateLimiter returns the rate limiter of the client, created on first use.
+func (m *Mnubo) rateLimiter() *rateLimiter {
+	m.rateLimiterOnce.Do(func() {
+		m.limiter = newRateLimiter(m.RateLimit)
+	})
+	return m.limiter
+}
+
+// buckets returns the buckets limiting an operation, like Events.Send.
+func (l *rateLimiter) buckets(operation string) requestLimiter {
+	var group *tokenBucket
+	switch operationGroup(operation) {
+	case groupIngestion:
+		group = l.ingestion
+	case groupSearch:
+		group = l.search
+	case groupModeler:
+		group = l.modeler
+	}
+
+	var buckets requestLimiter
+	for _, b := range []*tokenBucket{l.global, group} {
+		if b != nil {
+			buckets = append(buckets, b)
+		}
+	}
+	return buckets
+}
+
+// requestLimiter are the token buckets limiting a request.
+type requestLimiter []*tokenBucket
+
+// wait blocks until every bucket has a token for the request, or ctx is done.
+// The delay is checked again after waiting, as the rates may have been decreased in the meantime.
+// The tokens are given back if ctx is done first.
+func (buckets requestLimiter) wait(ctx context.Context) error {
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	reservations := make([]float64, len(buckets))
+	for i, b := range buckets {
+		reservations[i] = b.reserve(now)
+	}
+
+	for {
+		var delay time.Duration
+		for i, b := range buckets {
+			if d := b.delay(now, reservations[i]); d > delay {
+				delay = d
+			}
+		}
+		if delay <= 0 {
+			return nil
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case now = <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			now = time.Now()
+			for _, b := range buckets {
+				b.cancel(now)
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// adapt decreases the rates of the buckets when the platform throttles the request.
+func (buckets requestLimiter) adapt(res *http.Response) {
+	if res == nil || (res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable) {
+		return
+	}
+	now := time.Now()
+	for _, b := range buckets {
+		b.throttle(now)
+	}
+}
+
+// tokenBucket is a token bucket whose rate decreases when throttled, and recovers gradually.
+// Tokens can go below 0, as reservations of the requests waiting for their turn.
+type tokenBucket struct {
+	mu     sync.Mutex
+	config RateLimitConfig
+	limit  float64
+	burst  float64
+	rate   float64
+	tokens float64
+	// added is the total of the tokens added since the bucket was created, reservations wait for it to reach
+	// a given value so their delay follows the rate changes.
+	added float64
+	// last is the time tokens were last added.
+	last time.Time
+	// adjusted is the time the rate was last decreased or recovered.
+	adjusted time.Time
+}
+
+// newTokenBucket creates a full bucket, or returns nil if limit has no rate.
+func newTokenBucket(limit RateLimit, config RateLimitConfig) *tokenBucket {
+	if limit.Rate <= 0 {
+		return nil
+	}
+	burst := float64(limit.Burst)
+	if burst <= 0 {
+		burst = math.Ceil(limit.Rate)
+	}
+	return &tokenBucket{
+		config: config,
+		limit:  limit.Rate,
+		burst:  burst,
+		rate:   limit.Rate,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last call, and recovers the rate if it was decreased.
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+		b.added += elapsed.Seconds() * b.rate
+		b.last = now
+	}
+
+	if b.rate >= b.limit || b.config.Recovery <= 0 || b.config.RecoveryInterval <= 0 {
+		return
+	}
+	if steps := int(now.Sub(b.adjusted) / b.config.RecoveryInterval); steps > 0 {
+		b.rate = math.Min(b.limit, b.rate+float64(steps)*b.config.Recovery*b.limit)
+		b.adjusted = b.adjusted.Add(time.Duration(steps) * b.config.RecoveryInterval)
+	}
+}
+
+// reserve takes a token and returns the total of added tokens from which it can be used.
+func (b *tokenBucket) reserve(now time.Time) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	b.tokens--
+	return b.added + math.Max(0, -b.tokens)
+}
+
+// delay returns how long to wait at the current rate before the token reserved for added can be used.
+func (b *tokenBucket) delay(now time.Time, added float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	if added <= b.added {
+		return 0
+	}
+	// The delay overflows when the rate is close to 0.
+	d := (added - b.added) / b.rate * float64(time.Second)
+	if !(d < math.MaxInt64) {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// cancel gives back a reserved token which won't be used.
+func (b *tokenBucket) cancel(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// throttle decreases the rate, once per RecoveryInterval so concurrent throttled requests count once.
+func (b *tokenBucket) throttle(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.config.Decrease <= 0 || now.Sub(b.adjusted) < b.config.RecoveryInterval {
+		return
+	}
+	b.refill(now)
+	b.rate = math.Max(b.limit*b.config.Minimum, b.rate*b.config.Decrease)
+	b.adjusted = now
+}
+
+// currentRate returns the rate of the bucket, after recovery.
+func (b *tokenBucket) currentRate(now time.Time) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(now)
+	return b.rate
+}
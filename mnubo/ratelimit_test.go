@@ -0,0 +1,221 @@
+package mnubo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_Reserve(t *testing.T) {
+	b := newTokenBucket(RateLimit{Rate: 10, Burst: 2}, DefaultRateLimitConfig())
+	now := b.last
+
+	cases := []struct {
+		At       time.Duration
+		Expected time.Duration
+	}{
+		{0, 0},
+		{0, 0},
+		{0, 100 * time.Millisecond},
+		{0, 200 * time.Millisecond},
+		{time.Second, 0},
+		{time.Second, 0},
+		{time.Second, 100 * time.Millisecond},
+	}
+	for i, c := range cases {
+		at := now.Add(c.At)
+		if got := b.delay(at, b.reserve(at)); got != c.Expected {
+			t.Errorf("%d, expecting: %s, got: %s", i, c.Expected, got)
+		}
+	}
+
+	if newTokenBucket(RateLimit{}, DefaultRateLimitConfig()) != nil {
+		t.Errorf("expecting no bucket without a rate")
+	}
+	if b := newTokenBucket(RateLimit{Rate: 2.5}, DefaultRateLimitConfig()); b.burst != 3 {
+		t.Errorf("expecting burst to default to the rate rounded up, got: %f", b.burst)
+	}
+}
+
+func TestTokenBucket_Cancel(t *testing.T) {
+	b := newTokenBucket(RateLimit{Rate: 10, Burst: 1}, DefaultRateLimitConfig())
+	now := b.last
+
+	b.reserve(now)
+	b.reserve(now)
+	b.cancel(now)
+	if got := b.delay(now, b.reserve(now)); got != 100*time.Millisecond {
+		t.Errorf("expecting the cancelled token to be given back, got: %s", got)
+	}
+
+	b.cancel(now)
+	b.cancel(now)
+	b.cancel(now)
+	if b.tokens != b.burst {
+		t.Errorf("expecting tokens given back to stay below the burst, got: %f", b.tokens)
+	}
+}
+
+func TestTokenBucket_DelayFollowsRate(t *testing.T) {
+	b := newTokenBucket(RateLimit{Rate: 10, Burst: 1}, DefaultRateLimitConfig())
+	now := b.last
+
+	b.reserve(now)
+	reserved := b.reserve(now)
+	if got := b.delay(now, reserved); got != 100*time.Millisecond {
+		t.Errorf("expecting: %s, got: %s", 100*time.Millisecond, got)
+	}
+	b.throttle(now)
+	if got := b.delay(now, reserved); got != 200*time.Millisecond {
+		t.Errorf("expecting the delay to follow the decreased rate, got: %s", got)
+	}
+	if got := b.delay(now.Add(200*time.Millisecond), reserved); got != 0 {
+		t.Errorf("expecting the token to be available, got: %s", got)
+	}
+}
+
+func TestTokenBucket_DelayOverflow(t *testing.T) {
+	b := newTokenBucket(RateLimit{Rate: 1e-12, Burst: 1}, RateLimitConfig{})
+	now := b.last
+
+	b.reserve(now)
+	if got := b.delay(now, b.reserve(now)); got != math.MaxInt64 {
+		t.Errorf("expecting the longest delay, got: %s", got)
+	}
+}
+
+func TestTokenBucket_Adaptive(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	b := newTokenBucket(RateLimit{Rate: 10}, config)
+	now := time.Now()
+
+	b.throttle(now)
+	b.throttle(now.Add(time.Second))
+
+	cases := []struct {
+		At       time.Duration
+		Expected float64
+	}{
+		{time.Second, 5},
+		{config.RecoveryInterval, 6},
+		{3 * config.RecoveryInterval, 8},
+		{10 * config.RecoveryInterval, 10},
+	}
+	for i, c := range cases {
+		if got := b.currentRate(now.Add(c.At)); got != c.Expected {
+			t.Errorf("%d, expecting: %f, got: %f", i, c.Expected, got)
+		}
+	}
+
+	config.Recovery = 0
+	b = newTokenBucket(RateLimit{Rate: 10}, config)
+	for i := 0; i < 10; i++ {
+		b.throttle(now.Add(time.Duration(i) * config.RecoveryInterval))
+	}
+	if got := b.currentRate(now.Add(9 * config.RecoveryInterval)); got != 10*config.Minimum {
+		t.Errorf("expecting rate to stop at the minimum, got: %f", got)
+	}
+}
+
+func TestRateLimiter_Groups(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	config.Global = RateLimit{Rate: 100}
+	config.Ingestion = RateLimit{Rate: 10}
+	config.Modeler = RateLimit{Rate: 1}
+	l := newRateLimiter(config)
+
+	cases := []struct {
+		Operation string
+		Expected  requestLimiter
+	}{
+		{"Events.Send", requestLimiter{l.global, l.ingestion}},
+		{"Objects.Create", requestLimiter{l.global, l.ingestion}},
+		{"Owners.Claim", requestLimiter{l.global, l.ingestion}},
+		{"Search.CreateBasicQuery", requestLimiter{l.global}},
+		{"Model.Export", requestLimiter{l.global, l.modeler}},
+		{"Mnubo.GetAccessToken", requestLimiter{l.global}},
+		{"", requestLimiter{l.global}},
+	}
+	for i, c := range cases {
+		got := l.buckets(c.Operation)
+		if len(got) != len(c.Expected) {
+			t.Errorf("%d, expecting %d buckets, got: %d", i, len(c.Expected), len(got))
+			continue
+		}
+		for j := range got {
+			if got[j] != c.Expected[j] {
+				t.Errorf("%d, expecting bucket %d to be shared", i, j)
+			}
+		}
+	}
+
+	if got := newRateLimiter(DefaultRateLimitConfig()).buckets("Events.Send"); len(got) != 0 {
+		t.Errorf("expecting no limit by default, got: %+v", got)
+	}
+}
+
+func TestRateLimiter_Client(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.RateLimit.Global = RateLimit{Rate: 40, Burst: 1}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var results []Dataset
+			if err := m.Search.GetDatasets(&results); err != nil {
+				t.Errorf("client call failed: %+v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 6 requests at 40 per second, then 20 per second after the 429.
+	if elapsed := time.Since(start); elapsed < 125*time.Millisecond {
+		t.Errorf("expecting requests to be rate limited, took: %s", elapsed)
+	}
+	if rate := m.limiter.global.currentRate(time.Now()); rate != 20 {
+		t.Errorf("expecting rate to decrease after a 429, got: %f", rate)
+	}
+}
+
+func TestRateLimiter_ContextCancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.RateLimit.Search = RateLimit{Rate: 0.1, Burst: 1}
+
+	var results []Dataset
+	if err := m.Search.GetDatasets(&results); err != nil {
+		t.Fatalf("client call failed: %+v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := m.Search.GetDatasetsContext(ctx, &results)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expecting deadline exceeded while waiting for the limiter, got: %+v", err)
+	}
+}
@@ -0,0 +1,197 @@
+package mnubo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/cenkalti/backoff"
+)
+
+const (
+	DefaultRetryJitter = 0.5
+)
+
+// RetryPolicy decides which failed requests are retried and how long to wait between attempts.
+// Retries stop after ExponentialBackoffConfig.MaxElapsedTime, or after MaxAttempts.
+type RetryPolicy struct {
+	// MaxAttempts caps the number of attempts of a request, including the first one.
+	// There is no limit other than the elapsed time if MaxAttempts <= 0.
+	MaxAttempts int
+	// RetryableStatusCodes are the status codes of the responses to retry.
+	RetryableStatusCodes []int
+	// RetryNetworkErrors retries requests failing with transient network errors:
+	// connection reset or refused, timeouts and temporary DNS failures.
+	// Requests which are not idempotent, like Events.Send, are only retried when they failed to connect,
+	// as SmartObjects may have processed them otherwise.
+	RetryNetworkErrors bool
+	// Jitter randomizes the backoff intervals by +/- Jitter * interval, it must be between 0 and 1.
+	Jitter float64
+	// MaxRetryAfter caps the delay requested by Retry-After headers.
+	// Retry-After headers are ignored if it is 0.
+	MaxRetryAfter time.Duration
+	// Retryable replaces the default decision when set, for every request.
+	// statusCode is 0 when the request failed with err before getting a response.
+	Retryable func(statusCode int, err error) bool
+}
+
+// DefaultRetryPolicy retries 429, 502, 503 and 504 responses as well as transient network errors.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryNetworkErrors: true,
+		Jitter:             DefaultRetryJitter,
+		MaxRetryAfter:      DefaultBackoffMaxInterval,
+	}
+}
+
+// isRetryable returns true if a request failing with statusCode or err should be retried.
+func (p *RetryPolicy) isRetryable(statusCode int, err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(statusCode, err)
+	}
+	if err != nil {
+		return p.RetryNetworkErrors && isTransientNetworkError(err)
+	}
+	for _, sc := range p.RetryableStatusCodes {
+		if sc == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnsentRequestError returns true if a request failed before being sent, while connecting to SmartObjects.
+func isUnsentRequestError(err error) bool {
+	var opError *net.OpError
+	return errors.As(err, &opError) && opError.Op == "dial"
+}
+
+// isTransientNetworkError returns true for network errors which may not happen on a new attempt.
+func isTransientNetworkError(err error) bool {
+	var dnsError *net.DNSError
+	if errors.As(err, &dnsError) {
+		return dnsError.IsTimeout || dnsError.IsTemporary
+	}
+
+	var netError net.Error
+	if errors.As(err, &netError) && netError.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// AttemptError is given to ExponentialBackoffConfig.NotifyOnError when an attempt failed.
+// Use errors.As to get the attempt number.
+type AttemptError struct {
+	// Attempt is the number of the failed attempt, starting at 1.
+	Attempt int
+	Err     error
+}
+
+func (e *AttemptError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AttemptError) Unwrap() error {
+	return e.Err
+}
+
+// retryBackOff applies a RetryPolicy on top of the exponential backoff.
+// It stops once ctx is done, but unlike backoff.WithContext, it does not stop early
+// when the next interval would go past the ctx deadline, so retries always end with ctx.Err().
+type retryBackOff struct {
+	backoff.BackOff
+	ctx    context.Context
+	policy RetryPolicy
+	// idempotent is true if the request can be sent again after reaching SmartObjects.
+	idempotent bool
+	// attempt is the number of attempts made so far.
+	attempt int
+	// retryAfter is the delay requested by the last response.
+	retryAfter time.Duration
+}
+
+// newRetryBackOff creates the backoff used to retry a request.
+func newRetryBackOff(ctx context.Context, config ExponentialBackoffConfig, policy RetryPolicy) *retryBackOff {
+	b := backoff.NewExponentialBackOff()
+	b.MaxElapsedTime = config.MaxElapsedTime
+	b.RandomizationFactor = policy.Jitter
+
+	return &retryBackOff{
+		BackOff: b,
+		ctx:     ctx,
+		policy:  policy,
+	}
+}
+
+// isRetryableError returns true if an attempt failing with err before getting a response should be retried.
+func (b *retryBackOff) isRetryableError(err error) bool {
+	if !b.idempotent && b.policy.Retryable == nil && !isUnsentRequestError(err) {
+		return false
+	}
+	return b.policy.isRetryable(0, err)
+}
+
+func (b *retryBackOff) Context() context.Context {
+	return b.ctx
+}
+
+func (b *retryBackOff) NextBackOff() time.Duration {
+	if b.ctx.Err() != nil {
+		return backoff.Stop
+	}
+	if b.policy.MaxAttempts > 0 && b.attempt >= b.policy.MaxAttempts {
+		return backoff.Stop
+	}
+
+	next := b.BackOff.NextBackOff()
+	if next == backoff.Stop || b.retryAfter <= 0 || b.policy.MaxRetryAfter <= 0 {
+		return next
+	}
+	if b.retryAfter > b.policy.MaxRetryAfter {
+		return b.policy.MaxRetryAfter
+	}
+	return b.retryAfter
+}
+
+// notify wraps the NotifyOnError callback to give it the attempt number.
+func (b *retryBackOff) notify(notify backoff.Notify) backoff.Notify {
+	if notify == nil {
+		return nil
+	}
+	return func(err error, next time.Duration) {
+		notify(&AttemptError{Attempt: b.attempt, Err: err}, next)
+	}
+}
@@ -0,0 +1,337 @@
+package mnubo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	cases := []struct {
+		Value    string
+		Expected time.Duration
+	}{
+		{Value: "", Expected: 0},
+		{Value: "3", Expected: time.Second * 3},
+		{Value: "-1", Expected: 0},
+		{Value: "invalid", Expected: 0},
+		{Value: time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), Expected: 0},
+	}
+
+	for i, c := range cases {
+		if got := parseRetryAfter(c.Value); got != c.Expected {
+			t.Errorf("%d, expecting: %s, got: %s", i, c.Expected, got)
+		}
+	}
+
+	if got := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); got <= 0 || got > time.Minute {
+		t.Errorf("expecting a delay up to one minute, got: %s", got)
+	}
+}
+
+func TestRetryPolicy_StatusCodes(t *testing.T) {
+	cases := []struct {
+		StatusCode       int
+		ExpectedRequests int32
+	}{
+		{StatusCode: http.StatusTooManyRequests, ExpectedRequests: 2},
+		{StatusCode: http.StatusBadGateway, ExpectedRequests: 2},
+		{StatusCode: http.StatusServiceUnavailable, ExpectedRequests: 2},
+		{StatusCode: http.StatusGatewayTimeout, ExpectedRequests: 2},
+		{StatusCode: http.StatusBadRequest, ExpectedRequests: 1},
+		{StatusCode: http.StatusInternalServerError, ExpectedRequests: 1},
+	}
+
+	for _, c := range cases {
+		var requests int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "", c.StatusCode)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[]`))
+		}))
+		m := NewClientWithToken("TOKEN", ts.URL)
+
+		var results []Dataset
+		m.Search.GetDatasets(&results)
+		ts.Close()
+
+		if requests != c.ExpectedRequests {
+			t.Errorf("%d, expecting %d requests, got %d", c.StatusCode, c.ExpectedRequests, requests)
+		}
+	}
+}
+
+func TestRetryPolicy_RetryAfter(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	var notified []time.Duration
+	var attempts []int
+	m.ExponentialBackoff.NotifyOnError = func(err error, next time.Duration) {
+		var ae *AttemptError
+		if errors.As(err, &ae) {
+			attempts = append(attempts, ae.Attempt)
+		}
+		notified = append(notified, next)
+	}
+
+	start := time.Now()
+	var results []Dataset
+	err := m.Search.GetDatasets(&results)
+
+	if err != nil {
+		t.Errorf("client call failed: %+v", err)
+	}
+	if len(notified) != 1 || notified[0] != time.Second {
+		t.Errorf("expecting one retry after 1s, got: %+v", notified)
+	}
+	if len(attempts) != 1 || attempts[0] != 1 {
+		t.Errorf("expecting notification for attempt 1, got: %+v", attempts)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expecting to wait for Retry-After, waited %s", elapsed)
+	}
+}
+
+func TestRetryPolicy_MaxAttempts(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.RetryPolicy.MaxAttempts = 3
+	m.RetryPolicy.MaxRetryAfter = time.Millisecond
+
+	var results []Dataset
+	err := m.Search.GetDatasets(&results)
+
+	if err == nil {
+		t.Errorf("expecting an error after the last attempt")
+	}
+	if requests != 3 {
+		t.Errorf("expecting 3 requests, got %d", requests)
+	}
+}
+
+func TestRetryPolicy_NetworkErrors(t *testing.T) {
+	cases := []struct {
+		RetryNetworkErrors bool
+		ExpectedRequests   int32
+		ExpectError        bool
+	}{
+		{RetryNetworkErrors: true, ExpectedRequests: 2, ExpectError: false},
+		{RetryNetworkErrors: false, ExpectedRequests: 1, ExpectError: true},
+	}
+
+	for i, c := range cases {
+		var requests int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) == 1 {
+				// drop the connection without responding
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[]`))
+		}))
+		m := NewClientWithToken("TOKEN", ts.URL)
+		m.RetryPolicy.RetryNetworkErrors = c.RetryNetworkErrors
+
+		var results []Dataset
+		err := m.Search.GetDatasets(&results)
+		ts.Close()
+
+		if (err != nil) != c.ExpectError {
+			t.Errorf("%d, expecting error: %t, got: %+v", i, c.ExpectError, err)
+		}
+		if requests != c.ExpectedRequests {
+			t.Errorf("%d, expecting %d requests, got %d", i, c.ExpectedRequests, requests)
+		}
+	}
+}
+
+func TestRetryPolicy_NonIdempotent(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.RetryPolicy.MaxAttempts = 2
+	var attempts int
+	m.ExponentialBackoff.NotifyOnError = func(err error, _ time.Duration) {
+		attempts++
+	}
+
+	var results []SendEventsReport
+	err := m.Events.Send([]SimpleEvent{{XEventType: "event_type1"}}, SendEventsOptions{}, &results)
+	if err == nil || atomic.LoadInt32(&requests) != 1 || attempts != 0 {
+		t.Errorf("events may have been ingested, expecting a single request, got %d: %+v", atomic.LoadInt32(&requests), err)
+	}
+
+	// Queries can be sent again.
+	atomic.StoreInt32(&requests, 0)
+	err = m.Search.CreateBasicQueryWithString(`{}`, &SearchResults{})
+	if err == nil || atomic.LoadInt32(&requests) != 2 {
+		t.Errorf("expecting queries to be retried, got %d requests: %+v", atomic.LoadInt32(&requests), err)
+	}
+
+	// Events which never reached SmartObjects are sent again.
+	ts.Close()
+	attempts = 0
+	err = m.Events.Send([]SimpleEvent{{XEventType: "event_type1"}}, SendEventsOptions{}, &results)
+	if err == nil || attempts != 1 {
+		t.Errorf("expecting refused connections to be retried, got %d retries: %+v", attempts, err)
+	}
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+	m.RetryPolicy.Retryable = func(statusCode int, err error) bool {
+		return statusCode == http.StatusInternalServerError
+	}
+
+	var results []Dataset
+	err := m.Search.GetDatasets(&results)
+
+	if err != nil || requests != 2 {
+		t.Errorf("expecting the custom policy to retry once, got %d requests: %+v", requests, err)
+	}
+}
+
+func TestRetryPolicy_JsonErrorBody(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message":"quota exceeded"}`))
+			return
+		}
+		w.Write([]byte(`[{"key":"event"}]`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	var results []Dataset
+	err := m.Search.GetDatasets(&results)
+
+	if err != nil || len(results) != 1 || results[0].Key != "event" {
+		t.Errorf("error body should not be decoded into results, got: %+v, %+v", results, err)
+	}
+}
+
+func TestRetryResendsPayload(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Compression CompressionConfig
+		Send        func(m *Mnubo) error
+	}{
+		{
+			Name: "Events.Send",
+			Send: func(m *Mnubo) error {
+				var results []SendEventsReport
+				return m.Events.Send([]SimpleEvent{{XEventType: "event_type1"}}, SendEventsOptions{}, &results)
+			},
+		},
+		{
+			Name:        "Events.Send compressed",
+			Compression: CompressionConfig{Request: true},
+			Send: func(m *Mnubo) error {
+				var results []SendEventsReport
+				return m.Events.Send([]SimpleEvent{{XEventType: "event_type1"}}, SendEventsOptions{}, &results)
+			},
+		},
+		{
+			Name: "Objects.Update",
+			Send: func(m *Mnubo) error {
+				var results interface{}
+				return m.Objects.Update([]SimpleObject{{XDeviceID: "device", XObjectType: "type"}}, &results)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		var bodies [][]byte
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body io.Reader = r.Body
+			if r.Header.Get("Content-Encoding") == "gzip" {
+				gr, err := gzip.NewReader(r.Body)
+				if err != nil {
+					t.Errorf("%s, invalid gzip body: %+v", c.Name, err)
+					return
+				}
+				body = gr
+			}
+			b, _ := ioutil.ReadAll(body)
+			bodies = append(bodies, b)
+
+			if len(bodies) == 1 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`[]`))
+		}))
+		m := NewClientWithToken("TOKEN", ts.URL)
+		m.Compression = c.Compression
+		// http.Transport rewinds request bodies on its own, other round trippers don't.
+		m.HttpClient = &http.Client{
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				body, _ := ioutil.ReadAll(r.Body)
+				forwarded := r.Clone(r.Context())
+				forwarded.Body = ioutil.NopCloser(bytes.NewReader(body))
+				forwarded.GetBody = nil
+				return http.DefaultTransport.RoundTrip(forwarded)
+			}),
+		}
+
+		err := c.Send(m)
+		ts.Close()
+
+		if err != nil {
+			t.Errorf("%s, client call failed: %+v", c.Name, err)
+		}
+		if len(bodies) != 2 || len(bodies[0]) == 0 || !bytes.Equal(bodies[0], bodies[1]) {
+			t.Errorf("%s, expecting the same payload on both attempts, got: %q", c.Name, bodies)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package mnubo
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// Scopes of the access tokens, see Scopes.
+	ScopeAll   = "ALL"
+	ScopeRead  = "READ"
+	ScopeWrite = "WRITE"
+)
+
+// Endpoint groups, which can have their own rate limits and token scopes.
+const (
+	groupIngestion = "ingestion"
+	groupSearch    = "search"
+	groupModeler   = "modeler"
+)
+
+// operationGroup returns the endpoint group of an operation like Events.Send, or "" for other operations.
+func operationGroup(operation string) string {
+	switch operation[:strings.IndexByte(operation+".", '.')] {
+	case "Events", "Objects", "Owners":
+		return groupIngestion
+	case "Search":
+		return groupSearch
+	case "Model":
+		return groupModeler
+	default:
+		return ""
+	}
+}
+
+// Scopes are the scopes of the access tokens obtained with the client id and secret, per group of endpoints.
+// One token is cached per scope, so a service can use least-privilege tokens, like READ for search.
+// Scopes are not used with a static token or a TokenSource, whose tokens have their own scope.
+type Scopes struct {
+	// Default is the scope of the groups without one, ALL if it is empty.
+	Default string
+	// Ingestion is the scope of the Events, Objects and Owners endpoints.
+	Ingestion string
+	// Search is the scope of the Search endpoints.
+	Search string
+	// Modeler is the scope of the Model endpoints.
+	Modeler string
+}
+
+// forOperation returns the scope of the token to send with operation.
+func (s *Scopes) forOperation(operation string) string {
+	var scope string
+	switch operationGroup(operation) {
+	case groupIngestion:
+		scope = s.Ingestion
+	case groupSearch:
+		scope = s.Search
+	case groupModeler:
+		scope = s.Modeler
+	}
+	if scope == "" {
+		return s.defaultScope()
+	}
+	return scope
+}
+
+func (s *Scopes) defaultScope() string {
+	if s.Default == "" {
+		return ScopeAll
+	}
+	return s.Default
+}
+
+// newScopeTokenCache creates the cache of the tokens with scope, or with the default scope if scope is empty.
+func (m *Mnubo) newScopeTokenCache(scope string) *tokenCache {
+	// previous is the last token of the cache, which is expiring or was rejected when a new one is needed.
+	// Calls are serialized by the cache.
+	var previous string
+	c := newTokenCache(func(ctx context.Context) (AccessToken, error) {
+		s := scope
+		if s == "" {
+			s = m.Scopes.defaultScope()
+		}
+		fetch := func(ctx context.Context) (AccessToken, error) {
+			return m.fetchAccessToken(ctx, m.ClientId, m.ClientSecret, s)
+		}
+		if m.TokenCache == nil {
+			return fetch(ctx)
+		}
+
+		at, err := m.TokenCache.token(ctx, m.TokenCache.path(m.Host, m.ClientId, s), previous, fetch)
+		if err == nil {
+			previous = at.Value
+		}
+		return at, err
+	})
+	return c
+}
+
+// scopeTokenCache returns the cache of the tokens with scope, created on first use.
+func (m *Mnubo) scopeTokenCache(scope string) *tokenCache {
+	if scope == m.Scopes.defaultScope() {
+		return m.tokens
+	}
+
+	m.scopesMu.Lock()
+	defer m.scopesMu.Unlock()
+	c, ok := m.scopeTokens[scope]
+	if !ok {
+		if m.scopeTokens == nil {
+			m.scopeTokens = map[string]*tokenCache{}
+		}
+		c = m.newScopeTokenCache(scope)
+		m.scopeTokens[scope] = c
+	}
+	return c
+}
+
+// operationTokenSource returns the token source to authenticate operation with.
+func (m *Mnubo) operationTokenSource(operation string) TokenSource {
+	if m.tokenSource != TokenSource(m.tokens) {
+		return m.tokenSource
+	}
+	return m.scopeTokenCache(m.Scopes.forOperation(operation))
+}
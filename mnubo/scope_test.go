@@ -0,0 +1,113 @@
+package mnubo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestScopes_ForOperation(t *testing.T) {
+	scopes := Scopes{Ingestion: ScopeWrite, Search: ScopeRead}
+	cases := []struct {
+		Operation string
+		Expected  string
+	}{
+		{"Events.Send", ScopeWrite},
+		{"Objects.Create", ScopeWrite},
+		{"Owners.Claim", ScopeWrite},
+		{"Search.CreateBasicQuery", ScopeRead},
+		{"Model.Export", ScopeAll},
+		{"", ScopeAll},
+	}
+	for i, c := range cases {
+		if scope := scopes.forOperation(c.Operation); scope != c.Expected {
+			t.Errorf("%d, expecting: %s, got: %s", i, c.Expected, scope)
+		}
+	}
+}
+
+func TestScopes_Client(t *testing.T) {
+	var mu sync.Mutex
+	fetches := map[string]int{}
+	authorizations := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/oauth/token" {
+			r.ParseForm()
+			scope := r.Form.Get("scope")
+			fetches[scope]++
+			w.Write([]byte(`{"access_token":"TOKEN-` + scope + `","token_type":"Bearer","expires_in":3600000,"scope":"` + scope + `"}`))
+			return
+		}
+		authorizations[r.URL.Path] = r.Header.Get("Authorization")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	m, err := New(
+		WithHost(ts.URL),
+		WithClientCredentials("CLIENT_ID", "CLIENT_SECRET"),
+		WithScopes(Scopes{Ingestion: ScopeWrite, Search: ScopeRead}),
+	)
+	if err != nil {
+		t.Fatalf("unable to create client: %+v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var datasets []Dataset
+		if err := m.Search.GetDatasets(&datasets); err != nil {
+			t.Fatalf("search call failed: %+v", err)
+		}
+		var results []interface{}
+		if err := m.Events.Send([]map[string]string{{"x_event_type": "event-type"}}, SendEventsOptions{}, &results); err != nil {
+			t.Fatalf("ingestion call failed: %+v", err)
+		}
+		var timeseries []Timeseries
+		if err := m.Model.GetTimeseries(&timeseries); err != nil {
+			t.Fatalf("modeler call failed: %+v", err)
+		}
+	}
+
+	expected := map[string]string{
+		"/api/v3/search/datasets":  "Bearer TOKEN-READ",
+		"/api/v3/events":           "Bearer TOKEN-WRITE",
+		"/api/v3/model/timeseries": "Bearer TOKEN-ALL",
+	}
+	for path, authorization := range expected {
+		if authorizations[path] != authorization {
+			t.Errorf("%s, expecting: %s, got: %s", path, authorization, authorizations[path])
+		}
+	}
+	for _, scope := range []string{ScopeRead, ScopeWrite, ScopeAll} {
+		if fetches[scope] != 1 {
+			t.Errorf("%s, expecting one token fetch, got: %d", scope, fetches[scope])
+		}
+	}
+}
+
+func TestScopes_GetAccessTokenWithScope(t *testing.T) {
+	var authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/oauth/token" {
+			r.ParseForm()
+			w.Write([]byte(`{"access_token":"TOKEN-` + r.Form.Get("scope") + `","expires_in":3600000}`))
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	m, _ := New(WithHost(ts.URL), WithClientCredentials("CLIENT_ID", "CLIENT_SECRET"), WithScopes(Scopes{Search: ScopeRead}))
+	if _, err := m.GetAccessTokenWithScope(ScopeRead); err != nil {
+		t.Fatalf("unable to get token: %+v", err)
+	}
+	var datasets []Dataset
+	if err := m.Search.GetDatasets(&datasets); err != nil || authorization != "Bearer TOKEN-READ" {
+		t.Errorf("expecting the READ token for search, got: %s (%+v)", authorization, err)
+	}
+}
@@ -1,8 +1,11 @@
 package mnubo
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 )
 
 const (
@@ -43,6 +46,79 @@ type SearchResults struct {
 	Rows    [][]interface{}       `json:"rows"`
 }
 
+// ErrStopRows can be returned by SearchRows.OnRow to stop reading the rows without failing the query.
+var ErrStopRows = errors.New("stop reading rows")
+
+// SearchRows handles the rows of a query as they are decoded from the response, instead of holding all of
+// them in memory like SearchResults. It can be given as the results of the CreateBasicQuery functions.
+// The query is not retried once the response is received, as some rows may have been handled.
+type SearchRows struct {
+	// Columns are decoded before the first row, as SmartObjects sends them first.
+	Columns []SearchResultsColumn
+	// OnRow is called with each row, in order. Returning an error stops reading the rows and fails the query.
+	OnRow func(row []interface{}) error
+}
+
+// decodeStream implements responseStream.
+func (r *SearchRows) decodeStream(d *json.Decoder) error {
+	if err := expectDelim(d, '{'); err != nil {
+		return err
+	}
+	for d.More() {
+		key, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch key {
+		case "columns":
+			err = d.Decode(&r.Columns)
+		case "rows":
+			err = r.decodeRows(d)
+		default:
+			var skipped json.RawMessage
+			err = d.Decode(&skipped)
+		}
+		if errors.Is(err, ErrStopRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(d, '}')
+}
+
+func (r *SearchRows) decodeRows(d *json.Decoder) error {
+	if err := expectDelim(d, '['); err != nil {
+		return err
+	}
+	for d.More() {
+		var row []interface{}
+		if err := d.Decode(&row); err != nil {
+			return err
+		}
+		if r.OnRow != nil {
+			if err := r.OnRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	return expectDelim(d, ']')
+}
+
+// expectDelim reads the next token of d, which must be delim.
+func expectDelim(d *json.Decoder, delim json.Delim) error {
+	t, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("invalid search results: expecting %s, got: %v", delim, t)
+	}
+	return nil
+}
+
 // NewSearch creates a Search wrapper for Mnubo client.
 func NewSearch(m *Mnubo) *Search {
 	return &Search{
@@ -55,69 +131,127 @@ func NewSearch(m *Mnubo) *Search {
 // This method trust the user will use the proper structure based on its model.
 // See: https://smartobjects.mnubo.com/documentation/api_search.html#basic
 func (s *Search) CreateBasicQuery(mql interface{}, results interface{}) error {
+	return s.CreateBasicQueryContext(context.Background(), mql, results)
+}
+
+// CreateBasicQueryContext is like CreateBasicQuery but uses ctx to cancel the request and its retries.
+func (s *Search) CreateBasicQueryContext(ctx context.Context, mql interface{}, results interface{}) error {
 	payload, err := json.Marshal(mql)
 
 	if err != nil {
 		return fmt.Errorf("unable to marshal the mql: %s (%s)", mql, err)
 	}
 
-	return s.CreateBasicQueryWithBytes(payload, results)
+	return s.CreateBasicQueryWithBytesContext(ctx, payload, results)
 }
 
 // CreateBasicQueryWithString is a helper to use a string instead of creating a dedicated structure.
 func (s *Search) CreateBasicQueryWithString(mql string, results interface{}) error {
-	return s.CreateBasicQueryWithBytes([]byte(mql), results)
+	return s.CreateBasicQueryWithStringContext(context.Background(), mql, results)
+}
+
+// CreateBasicQueryWithStringContext is like CreateBasicQueryWithString but uses ctx to cancel the request and its retries.
+func (s *Search) CreateBasicQueryWithStringContext(ctx context.Context, mql string, results interface{}) error {
+	return s.CreateBasicQueryWithBytesContext(ctx, []byte(mql), results)
 }
 
 // CreateBasicQueryWithString is a helper to use an array of bytes.
 func (s *Search) CreateBasicQueryWithBytes(mql []byte, results interface{}) error {
+	return s.CreateBasicQueryWithBytesContext(context.Background(), mql, results)
+}
+
+// CreateBasicQueryWithBytesContext is like CreateBasicQueryWithBytes but uses ctx to cancel the request and its retries.
+func (s *Search) CreateBasicQueryWithBytesContext(ctx context.Context, mql []byte, results interface{}) error {
 	cr := ClientRequest{
+		operation:   "Search.CreateBasicQuery",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/basic", searchPath),
 		payload:     mql,
+		idempotent:  true,
 	}
 
-	return s.Mnubo.doRequestWithAuthentication(cr, results)
+	return s.Mnubo.doRequestWithAuthentication(ctx, cr, results)
+}
+
+// Rows returns an iterator over the rows of an MQL query, decoded as they are received, see SearchRows.
+// Iteration stops after the first error.
+func (s *Search) Rows(ctx context.Context, mql interface{}) iter.Seq2[[]interface{}, error] {
+	return func(yield func([]interface{}, error) bool) {
+		rows := &SearchRows{
+			OnRow: func(row []interface{}) error {
+				if !yield(row, nil) {
+					return ErrStopRows
+				}
+				return nil
+			},
+		}
+		if err := s.CreateBasicQueryContext(ctx, mql, rows); err != nil {
+			yield(nil, err)
+		}
+	}
 }
 
 // ValidateQuery is the main function to use to understand why an MQL query is not valid.
 // See: https://smartobjects.mnubo.com/documentation/api_search.html#validate
 func (s *Search) ValidateQuery(mql interface{}, results *QueryValidation) error {
+	return s.ValidateQueryContext(context.Background(), mql, results)
+}
+
+// ValidateQueryContext is like ValidateQuery but uses ctx to cancel the request and its retries.
+func (s *Search) ValidateQueryContext(ctx context.Context, mql interface{}, results *QueryValidation) error {
 	payload, err := json.Marshal(mql)
 
 	if err != nil {
 		return fmt.Errorf("unable to marshal the mql: %s (%s)", mql, err)
 	}
 
-	return s.ValidateQueryWithBytes(payload, results)
+	return s.ValidateQueryWithBytesContext(ctx, payload, results)
 }
 
 // ValidateQueryWithString is a helper that allows to send a string instead of creating
 // a dedicated structure.
 func (s *Search) ValidateQueryWithString(mql string, results *QueryValidation) error {
-	return s.ValidateQueryWithBytes([]byte(mql), results)
+	return s.ValidateQueryWithStringContext(context.Background(), mql, results)
+}
+
+// ValidateQueryWithStringContext is like ValidateQueryWithString but uses ctx to cancel the request and its retries.
+func (s *Search) ValidateQueryWithStringContext(ctx context.Context, mql string, results *QueryValidation) error {
+	return s.ValidateQueryWithBytesContext(ctx, []byte(mql), results)
 }
 
 // ValidateQueryWithBytes is a helper that allows to send bytes.
 func (s *Search) ValidateQueryWithBytes(mql []byte, results *QueryValidation) error {
+	return s.ValidateQueryWithBytesContext(context.Background(), mql, results)
+}
+
+// ValidateQueryWithBytesContext is like ValidateQueryWithBytes but uses ctx to cancel the request and its retries.
+func (s *Search) ValidateQueryWithBytesContext(ctx context.Context, mql []byte, results *QueryValidation) error {
 	cr := ClientRequest{
+		operation:   "Search.ValidateQuery",
 		method:      "POST",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/validateQuery", searchPath),
 		payload:     mql,
+		idempotent:  true,
 	}
 
-	return s.Mnubo.doRequestWithAuthentication(cr, results)
+	return s.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
 
 // GetDatasets returns an array of SmartObjects datasets to perform queries.
 func (s *Search) GetDatasets(results *[]Dataset) error {
+	return s.GetDatasetsContext(context.Background(), results)
+}
+
+// GetDatasetsContext is like GetDatasets but uses ctx to cancel the request and its retries.
+func (s *Search) GetDatasetsContext(ctx context.Context, results *[]Dataset) error {
 	cr := ClientRequest{
+		operation:   "Search.GetDatasets",
 		method:      "GET",
 		contentType: "application/json",
 		path:        fmt.Sprintf("%s/datasets", searchPath),
 	}
 
-	return s.Mnubo.doRequestWithAuthentication(cr, results)
+	return s.Mnubo.doRequestWithAuthentication(ctx, cr, results)
 }
@@ -0,0 +1,411 @@
+package mnubo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTokenRefreshMargin is how long before ExpiresAt an access token gets refreshed.
+	// Tokens with a short lifetime are refreshed after 90% of their lifetime instead.
+	DefaultTokenRefreshMargin = time.Minute
+
+	// Environment variables read by NewEnvTokenSource.
+	EnvClientId     = "MNUBO_CLIENT_ID"
+	EnvClientSecret = "MNUBO_CLIENT_SECRET"
+	EnvClientToken  = "MNUBO_CLIENT_TOKEN"
+)
+
+// ErrNoCredentials is returned by token sources which could not find any credentials.
+var ErrNoCredentials = errors.New("no SmartObjects credentials available")
+
+// TokenSource provides the access tokens used to authenticate requests sent to SmartObjects.
+// It is similar to golang.org/x/oauth2.TokenSource, with a context to cancel token requests.
+// Implementations must be safe for concurrent use.
+type TokenSource interface {
+	Token(ctx context.Context) (AccessToken, error)
+}
+
+// tokenSourceBinder is implemented by token sources which need a client to request tokens.
+type tokenSourceBinder interface {
+	bind(m *Mnubo)
+}
+
+// bindTokenSource gives ts access to the client if it needs one.
+func bindTokenSource(ts TokenSource, m *Mnubo) {
+	if b, ok := ts.(tokenSourceBinder); ok {
+		b.bind(m)
+	}
+}
+
+// tokenInvalidator is implemented by token sources which can discard a token rejected by the platform.
+type tokenInvalidator interface {
+	// invalidate discards at and returns true if a new token can be obtained.
+	invalidate(at AccessToken) bool
+}
+
+// invalidateToken discards at from ts and returns true if a new token can be obtained.
+func invalidateToken(ts TokenSource, at AccessToken) bool {
+	if i, ok := ts.(tokenInvalidator); ok {
+		return i.invalidate(at)
+	}
+	return false
+}
+
+type staticTokenSource struct {
+	token AccessToken
+}
+
+// StaticTokenSource returns a TokenSource always returning the same token, which never expires.
+func StaticTokenSource(token string) TokenSource {
+	return &staticTokenSource{
+		token: AccessToken{
+			Value:     token,
+			TokenType: "Bearer",
+		},
+	}
+}
+
+// Token implements TokenSource.
+func (s *staticTokenSource) Token(ctx context.Context) (AccessToken, error) {
+	return s.token, nil
+}
+
+// ClientCredentialsTokenSource exchanges a client id and secret for access tokens.
+// Tokens are cached and refreshed shortly before they expire.
+// It requests tokens through the client it is given to, and must not be shared by several clients.
+type ClientCredentialsTokenSource struct {
+	ClientId     string
+	ClientSecret string
+	Scope        string
+	client       *Mnubo
+	tokens       *tokenCache
+}
+
+// NewClientCredentialsTokenSource creates a TokenSource from a client id and secret, with scope ALL.
+func NewClientCredentialsTokenSource(id string, secret string) *ClientCredentialsTokenSource {
+	s := &ClientCredentialsTokenSource{
+		ClientId:     id,
+		ClientSecret: secret,
+		Scope:        "ALL",
+	}
+	s.tokens = newTokenCache(s.fetch)
+	return s
+}
+
+func (s *ClientCredentialsTokenSource) bind(m *Mnubo) {
+	s.client = m
+}
+
+func (s *ClientCredentialsTokenSource) fetch(ctx context.Context) (AccessToken, error) {
+	if s.client == nil {
+		return AccessToken{}, errors.New("client credentials token source is not used by a client")
+	}
+	return s.client.fetchAccessToken(ctx, s.ClientId, s.ClientSecret, s.Scope)
+}
+
+// Token implements TokenSource.
+func (s *ClientCredentialsTokenSource) Token(ctx context.Context) (AccessToken, error) {
+	return s.tokens.Token(ctx)
+}
+
+func (s *ClientCredentialsTokenSource) invalidate(at AccessToken) bool {
+	return s.tokens.invalidate(at)
+}
+
+// EnvTokenSource reads credentials from the environment every time a token is needed.
+// The credentials are a static token in MNUBO_CLIENT_TOKEN, or MNUBO_CLIENT_ID and MNUBO_CLIENT_SECRET.
+// Like NewFromEnv, it fails with ErrInvalidConfig while both are set.
+type EnvTokenSource struct {
+	mu          sync.Mutex
+	client      *Mnubo
+	credentials *ClientCredentialsTokenSource
+}
+
+// NewEnvTokenSource creates a TokenSource based on environment variables.
+func NewEnvTokenSource() *EnvTokenSource {
+	return &EnvTokenSource{}
+}
+
+func (s *EnvTokenSource) bind(m *Mnubo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.client = m
+	if s.credentials != nil {
+		s.credentials.bind(m)
+	}
+}
+
+// Token implements TokenSource.
+func (s *EnvTokenSource) Token(ctx context.Context) (AccessToken, error) {
+	token, id, secret := os.Getenv(EnvClientToken), os.Getenv(EnvClientId), os.Getenv(EnvClientSecret)
+	if token != "" && (id != "" || secret != "") {
+		return AccessToken{}, invalidConfig("only one of %s, or %s and %s can be set", EnvClientToken, EnvClientId, EnvClientSecret)
+	}
+	if token != "" {
+		return StaticTokenSource(token).Token(ctx)
+	}
+
+	if id == "" || secret == "" {
+		return AccessToken{}, fmt.Errorf("%w: %s, or %s and %s are not set", ErrNoCredentials, EnvClientToken, EnvClientId, EnvClientSecret)
+	}
+
+	s.mu.Lock()
+	// Rotated credentials get a new cache, so the previous token is not reused.
+	if s.credentials == nil || s.credentials.ClientId != id || s.credentials.ClientSecret != secret {
+		s.credentials = NewClientCredentialsTokenSource(id, secret)
+		s.credentials.bind(s.client)
+	}
+	credentials := s.credentials
+	s.mu.Unlock()
+
+	return credentials.Token(ctx)
+}
+
+func (s *EnvTokenSource) invalidate(at AccessToken) bool {
+	s.mu.Lock()
+	credentials := s.credentials
+	s.mu.Unlock()
+
+	// Static tokens can't be refreshed, only the ones obtained with client credentials.
+	if credentials == nil || os.Getenv(EnvClientToken) != "" {
+		return false
+	}
+	return credentials.invalidate(at)
+}
+
+// FileTokenSource reads a static token from a file, which is read again when it changes.
+// Leading and trailing whitespaces are ignored.
+type FileTokenSource struct {
+	Path    string
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	token   AccessToken
+}
+
+// NewFileTokenSource creates a TokenSource reading its token from path.
+func NewFileTokenSource(path string) *FileTokenSource {
+	return &FileTokenSource{
+		Path: path,
+	}
+}
+
+// Token implements TokenSource.
+func (s *FileTokenSource) Token(ctx context.Context) (AccessToken, error) {
+	fi, err := os.Stat(s.Path)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("unable to read token file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.Value != "" && fi.ModTime().Equal(s.modTime) && fi.Size() == s.size {
+		return s.token, nil
+	}
+
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("unable to read token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return AccessToken{}, fmt.Errorf("%w: token file %s is empty", ErrNoCredentials, s.Path)
+	}
+
+	s.token = AccessToken{
+		Value:     token,
+		TokenType: "Bearer",
+	}
+	s.modTime = fi.ModTime()
+	s.size = fi.Size()
+	return s.token, nil
+}
+
+// invalidate forces the file to be read again, as it may have been rotated
+// without changing its modification time and size. It returns true if the file now holds another token.
+func (s *FileTokenSource) invalidate(at AccessToken) bool {
+	s.mu.Lock()
+	if s.token.Value == at.Value {
+		s.token = AccessToken{}
+	}
+	s.mu.Unlock()
+
+	token, err := s.Token(context.Background())
+	return err == nil && token.Value != at.Value
+}
+
+type chainTokenSource struct {
+	sources []TokenSource
+}
+
+// ChainTokenSources returns a TokenSource trying each source in order, until one returns a token.
+func ChainTokenSources(sources ...TokenSource) TokenSource {
+	return &chainTokenSource{
+		sources: sources,
+	}
+}
+
+func (s *chainTokenSource) bind(m *Mnubo) {
+	for _, ts := range s.sources {
+		bindTokenSource(ts, m)
+	}
+}
+
+// Token implements TokenSource.
+func (s *chainTokenSource) Token(ctx context.Context) (AccessToken, error) {
+	var errs []string
+	for _, ts := range s.sources {
+		at, err := ts.Token(ctx)
+		if err == nil {
+			return at, nil
+		}
+		if ctx.Err() != nil {
+			return AccessToken{}, ctx.Err()
+		}
+		errs = append(errs, err.Error())
+	}
+	return AccessToken{}, fmt.Errorf("%w: %s", ErrNoCredentials, strings.Join(errs, "; "))
+}
+
+func (s *chainTokenSource) invalidate(at AccessToken) bool {
+	invalidated := false
+	for _, ts := range s.sources {
+		if invalidateToken(ts, at) {
+			invalidated = true
+		}
+	}
+	return invalidated
+}
+
+// needsRefresh returns true if an access token is missing, expired or about to expire.
+func (at *AccessToken) needsRefresh() bool {
+	if at.Value == "" {
+		return true
+	}
+	margin := DefaultTokenRefreshMargin
+	if lifetime := time.Duration(at.ExpiresIn) * time.Millisecond; lifetime/10 < margin {
+		margin = lifetime / 10
+	}
+	return time.Now().Add(margin).After(at.ExpiresAt)
+}
+
+// tokenRefresh is a refresh in progress, shared by every caller waiting for a new token.
+type tokenRefresh struct {
+	done  chan struct{}
+	token AccessToken
+	err   error
+}
+
+// tokenCache caches an AccessToken and serializes its refreshes.
+// Concurrent callers needing a new token share a single call to fetch, and callers
+// holding a token that is about to expire keep using it while the refresh is in flight.
+type tokenCache struct {
+	mu       sync.Mutex
+	token    AccessToken
+	inflight *tokenRefresh
+	fetch    func(ctx context.Context) (AccessToken, error)
+}
+
+// newTokenCache creates a tokenCache using fetch to obtain new tokens.
+func newTokenCache(fetch func(ctx context.Context) (AccessToken, error)) *tokenCache {
+	return &tokenCache{
+		fetch: fetch,
+	}
+}
+
+// Token returns the cached token, refreshing it first if needed.
+func (c *tokenCache) Token(ctx context.Context) (AccessToken, error) {
+	for {
+		c.mu.Lock()
+		cached := c.token
+		if !cached.needsRefresh() {
+			c.mu.Unlock()
+			return cached, nil
+		}
+
+		r := c.inflight
+		if r == nil {
+			r = &tokenRefresh{done: make(chan struct{})}
+			c.inflight = r
+			c.mu.Unlock()
+
+			c.refresh(ctx, r)
+			return r.token, r.err
+		}
+		c.mu.Unlock()
+
+		// Another caller is refreshing, keep using the current token if it is still valid.
+		if !cached.hasExpired() && cached.Value != "" {
+			return cached, nil
+		}
+
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return AccessToken{}, ctx.Err()
+		}
+
+		if r.err == nil {
+			return r.token, nil
+		}
+		// The refresh was cancelled by the caller that started it, try again with our own context.
+		if errors.Is(r.err, context.Canceled) || errors.Is(r.err, context.DeadlineExceeded) {
+			continue
+		}
+		return AccessToken{}, r.err
+	}
+}
+
+// refresh fetches a new token for r. The refresh is always completed, even if fetch panics,
+// so the callers waiting for it are not blocked forever.
+func (c *tokenCache) refresh(ctx context.Context, r *tokenRefresh) {
+	defer func() {
+		c.mu.Lock()
+		if r.err == nil {
+			c.token = r.token
+		}
+		c.inflight = nil
+		c.mu.Unlock()
+		close(r.done)
+	}()
+
+	// Returned to the waiting callers if fetch panics.
+	r.err = errors.New("access token refresh did not complete")
+	r.token, r.err = c.fetch(ctx)
+}
+
+// invalidate discards the cached token if it is at, so the next call to Token fetches a new one.
+// A token already replaced by another caller is kept. It returns true if at was discarded, or if
+// another token is cached or being fetched.
+func (c *tokenCache) invalidate(at AccessToken) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token.Value == at.Value {
+		c.token = AccessToken{}
+		return true
+	}
+	return c.token.Value != "" || c.inflight != nil
+}
+
+// store replaces the cached token.
+func (c *tokenCache) store(at AccessToken) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = at
+}
+
+// cached returns the cached token without refreshing it.
+func (c *tokenCache) cached() AccessToken {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
@@ -0,0 +1,394 @@
+package mnubo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAccessToken_NeedsRefresh(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		Token    AccessToken
+		Expected bool
+	}{
+		{
+			Token:    AccessToken{},
+			Expected: true,
+		},
+		{
+			Token:    AccessToken{Value: "a", ExpiresIn: 3600000, ExpiresAt: now.Add(time.Hour)},
+			Expected: false,
+		},
+		{
+			Token:    AccessToken{Value: "a", ExpiresIn: 3600000, ExpiresAt: now.Add(time.Second * 30)},
+			Expected: true,
+		},
+		{
+			Token:    AccessToken{Value: "a", ExpiresIn: 10000, ExpiresAt: now.Add(time.Second * 5)},
+			Expected: false,
+		},
+		{
+			Token:    AccessToken{Value: "a", ExpiresIn: 10000, ExpiresAt: now.Add(time.Millisecond * 500)},
+			Expected: true,
+		},
+	}
+
+	for i, c := range cases {
+		if got := c.Token.needsRefresh(); got != c.Expected {
+			t.Errorf("%d, expecting: %t, got: %t", i, c.Expected, got)
+		}
+	}
+}
+
+func TestTokenCache_SingleFlight(t *testing.T) {
+	var tokenCalls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/token" {
+			n := atomic.AddInt32(&tokenCalls, 1)
+			time.Sleep(time.Millisecond * 50)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"access_token":"token-%d","expires_in":3600000}`, n)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			http.Error(w, "", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+	m := NewClient("id", "secret", ts.URL)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 300)
+	for i := 0; i < 300; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var results []SendEventsReport
+			errs <- m.Events.Send([]SimpleEvent{{XEventType: "event_type1"}}, SendEventsOptions{}, &results)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("client call failed: %+v", err)
+		}
+	}
+	if tokenCalls != 1 {
+		t.Errorf("expecting 1 token request, got %d", tokenCalls)
+	}
+}
+
+func TestTokenCache_ProactiveRefresh(t *testing.T) {
+	var fetches int32
+	c := newTokenCache(func(ctx context.Context) (AccessToken, error) {
+		n := atomic.AddInt32(&fetches, 1)
+		return AccessToken{Value: fmt.Sprintf("token-%d", n), ExpiresIn: 3600000, ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+	c.store(AccessToken{Value: "old", ExpiresIn: 3600000, ExpiresAt: time.Now().Add(time.Second * 10)})
+
+	at, err := c.Token(context.Background())
+
+	if err != nil {
+		t.Errorf("unable to get token: %+v", err)
+	}
+	if at.Value != "token-1" || fetches != 1 {
+		t.Errorf("token about to expire should be refreshed, got %s after %d fetches", at.Value, fetches)
+	}
+}
+
+func TestTokenCache_ValidTokenDuringRefresh(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	c := newTokenCache(func(ctx context.Context) (AccessToken, error) {
+		close(started)
+		<-release
+		return AccessToken{Value: "new", ExpiresIn: 3600000, ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+	c.store(AccessToken{Value: "old", ExpiresIn: 3600000, ExpiresAt: time.Now().Add(time.Second * 10)})
+
+	done := make(chan struct{})
+	go func() {
+		c.Token(context.Background())
+		close(done)
+	}()
+	<-started
+
+	at, err := c.Token(context.Background())
+	if err != nil || at.Value != "old" {
+		t.Errorf("expecting the still valid token while refreshing, got: %s, %+v", at.Value, err)
+	}
+
+	close(release)
+	<-done
+}
+
+func TestTokenCache_CancelledLeader(t *testing.T) {
+	var fetches int32
+	started := make(chan struct{})
+	c := newTokenCache(func(ctx context.Context) (AccessToken, error) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return AccessToken{}, ctx.Err()
+		}
+		return AccessToken{Value: "new", ExpiresIn: 3600000, ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error)
+	go func() {
+		_, err := c.Token(ctx)
+		leader <- err
+	}()
+	<-started
+
+	waiter := make(chan AccessToken)
+	go func() {
+		at, _ := c.Token(context.Background())
+		waiter <- at
+	}()
+	time.Sleep(time.Millisecond * 20)
+	cancel()
+
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("expecting canceled error for the leader, got: %+v", err)
+	}
+	if at := <-waiter; at.Value != "new" {
+		t.Errorf("waiter should fetch its own token after the leader was cancelled, got: %+v", at)
+	}
+}
+
+func TestTokenCache_PanickingFetch(t *testing.T) {
+	var fetches int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c := newTokenCache(func(ctx context.Context) (AccessToken, error) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			close(started)
+			<-release
+			panic("fetch failed")
+		}
+		return AccessToken{Value: "new", ExpiresIn: 3600000, ExpiresAt: time.Now().Add(time.Hour)}, nil
+	})
+
+	leader := make(chan interface{})
+	go func() {
+		defer func() { leader <- recover() }()
+		c.Token(context.Background())
+	}()
+	<-started
+
+	waiter := make(chan error)
+	go func() {
+		_, err := c.Token(context.Background())
+		waiter <- err
+	}()
+	time.Sleep(time.Millisecond * 20)
+	close(release)
+
+	if r := <-leader; r == nil {
+		t.Errorf("expecting the panic to reach the leader")
+	}
+	select {
+	case err := <-waiter:
+		if err == nil {
+			t.Errorf("expecting the waiter to get an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("waiter is blocked after the refresh panicked")
+	}
+	if at, err := c.Token(context.Background()); err != nil || at.Value != "new" {
+		t.Errorf("expecting a new refresh, got: %+v, %+v", at, err)
+	}
+}
+
+func TestTokenCache_Invalidate(t *testing.T) {
+	c := newTokenCache(nil)
+	rejected := AccessToken{Value: "rejected"}
+	c.store(rejected)
+
+	if !c.invalidate(rejected) {
+		t.Errorf("expecting the cached token to be discarded")
+	}
+	if c.invalidate(rejected) {
+		t.Errorf("expecting no new token once the token was discarded")
+	}
+	c.store(AccessToken{Value: "new"})
+	if !c.invalidate(rejected) {
+		t.Errorf("expecting the token cached by another caller to be used")
+	}
+	if at := c.cached(); at.Value != "new" {
+		t.Errorf("expecting the token of another caller to be kept, got: %+v", at)
+	}
+}
+
+func TestStaticTokenSource(t *testing.T) {
+	at, err := StaticTokenSource("TOKEN").Token(context.Background())
+
+	if err != nil || at.Value != "TOKEN" {
+		t.Errorf("expecting static token, got: %+v, %+v", at, err)
+	}
+}
+
+func TestFileTokenSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	s := NewFileTokenSource(path)
+
+	if _, err := s.Token(context.Background()); err == nil {
+		t.Errorf("missing token file should return an error")
+	}
+
+	cases := []struct {
+		Content  string
+		Expected string
+	}{
+		{Content: "first\n", Expected: "first"},
+		{Content: "  rotated-token  \n", Expected: "rotated-token"},
+	}
+
+	for i, c := range cases {
+		if err := ioutil.WriteFile(path, []byte(c.Content), 0600); err != nil {
+			t.Fatalf("%d, unable to write token file: %+v", i, err)
+		}
+		// make sure the modification time changes on coarse grained file systems
+		mt := time.Now().Add(time.Duration(i) * time.Second)
+		os.Chtimes(path, mt, mt)
+
+		at, err := s.Token(context.Background())
+		if err != nil || at.Value != c.Expected {
+			t.Errorf("%d, expecting: %s, got: %+v, %+v", i, c.Expected, at, err)
+		}
+	}
+
+	// A rejected token is only replayed if the file holds another one.
+	at, _ := s.Token(context.Background())
+	if s.invalidate(at) {
+		t.Errorf("expecting no new token when the file did not change")
+	}
+	fi, _ := os.Stat(path)
+	if err := ioutil.WriteFile(path, []byte("  rotated-other  \n"), 0600); err != nil {
+		t.Fatalf("unable to write token file: %+v", err)
+	}
+	os.Chtimes(path, fi.ModTime(), fi.ModTime())
+	if !s.invalidate(at) {
+		t.Errorf("expecting a new token when the file was rotated")
+	}
+	if at, err := s.Token(context.Background()); err != nil || at.Value != "rotated-other" {
+		t.Errorf("expecting rotated token, got: %+v, %+v", at, err)
+	}
+}
+
+func TestEnvTokenSource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _, _ := r.BasicAuth()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-for-%s","expires_in":3600000}`, id)
+	}))
+	defer ts.Close()
+
+	s := NewEnvTokenSource()
+	NewClientWithTokenSource(s, ts.URL)
+
+	t.Setenv(EnvClientToken, "")
+	t.Setenv(EnvClientId, "")
+	t.Setenv(EnvClientSecret, "")
+	if _, err := s.Token(context.Background()); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expecting ErrNoCredentials, got: %+v", err)
+	}
+
+	cases := []struct {
+		Env      map[string]string
+		Expected string
+	}{
+		{
+			Env:      map[string]string{EnvClientId: "id1", EnvClientSecret: "secret"},
+			Expected: "token-for-id1",
+		},
+		{
+			Env:      map[string]string{EnvClientId: "id2", EnvClientSecret: "secret"},
+			Expected: "token-for-id2",
+		},
+		{
+			Env:      map[string]string{EnvClientToken: "static", EnvClientId: "", EnvClientSecret: ""},
+			Expected: "static",
+		},
+	}
+
+	for i, c := range cases {
+		for k, v := range c.Env {
+			t.Setenv(k, v)
+		}
+		at, err := s.Token(context.Background())
+		if err != nil || at.Value != c.Expected {
+			t.Errorf("%d, expecting: %s, got: %+v, %+v", i, c.Expected, at, err)
+		}
+	}
+
+	// Like NewFromEnv, conflicting credentials are rejected.
+	t.Setenv(EnvClientId, "id1")
+	t.Setenv(EnvClientSecret, "secret")
+	if _, err := s.Token(context.Background()); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expecting ErrInvalidConfig, got: %+v", err)
+	}
+}
+
+func TestChainTokenSources(t *testing.T) {
+	s := ChainTokenSources(
+		NewFileTokenSource(filepath.Join(t.TempDir(), "missing")),
+		StaticTokenSource("fallback"),
+	)
+
+	at, err := s.Token(context.Background())
+	if err != nil || at.Value != "fallback" {
+		t.Errorf("expecting fallback token, got: %+v, %+v", at, err)
+	}
+
+	_, err = ChainTokenSources(NewFileTokenSource(filepath.Join(t.TempDir(), "missing"))).Token(context.Background())
+	if !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expecting ErrNoCredentials, got: %+v", err)
+	}
+}
+
+func TestNewClientWithTokenSource(t *testing.T) {
+	var authorization string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/oauth/token" {
+			if id, secret, _ := r.BasicAuth(); id != "id" || secret != "secret" {
+				http.Error(w, "", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"access_token":"from-credentials","expires_in":3600000}`))
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	m := NewClientWithTokenSource(NewClientCredentialsTokenSource("id", "secret"), ts.URL)
+	var results []Dataset
+	err := m.Search.GetDatasets(&results)
+
+	if err != nil {
+		t.Errorf("client call failed: %+v", err)
+	}
+	if authorization != "Bearer from-credentials" {
+		t.Errorf("expecting token from the token source, got: %s", authorization)
+	}
+}
@@ -0,0 +1,237 @@
+package mnubo
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// tokenCacheLockRetry is how often a process waiting for the lock of a cached token tries again.
+	tokenCacheLockRetry = time.Millisecond * 20
+	// tokenCacheStaleLock is how old the lock of a cached token must be to be considered left by a crashed process.
+	tokenCacheStaleLock = time.Minute
+	// tokenCacheLockRefresh is how often the lock of a cached token is touched while its process holds it,
+	// so it never gets stale while the token is being fetched.
+	tokenCacheLockRefresh = tokenCacheStaleLock / 4
+)
+
+// FileTokenCache persists the access tokens obtained with a client id and secret in a directory,
+// so short-lived processes like CLI or cron jobs reuse a token instead of requesting a new one on every run.
+// Tokens are stored per host, client id and scope in files only readable by their owner.
+// A lock file per token ensures concurrent processes request a single new token when it expires.
+type FileTokenCache struct {
+	Dir string
+}
+
+// NewFileTokenCache creates a FileTokenCache storing tokens in dir, which is created if needed.
+func NewFileTokenCache(dir string) *FileTokenCache {
+	return &FileTokenCache{
+		Dir: dir,
+	}
+}
+
+// DefaultTokenCacheDir returns the mnubo/tokens directory of the user cache directory, like ~/.cache on Linux.
+func DefaultTokenCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mnubo", "tokens"), nil
+}
+
+// path returns the file of the token of a host, client id and scope.
+func (c *FileTokenCache) path(host string, clientId string, scope string) string {
+	sum := sha256.Sum256([]byte(host + "\n" + clientId + "\n" + scope))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// token returns the cached token of path, unless it is about to expire or it is previous, which was rejected
+// or is expiring. Otherwise it fetches a new token and caches it, while holding the lock of path.
+// Failing to use the cache does not fail the request, fetch is called instead.
+func (c *FileTokenCache) token(ctx context.Context, path string, previous string, fetch func(ctx context.Context) (AccessToken, error)) (AccessToken, error) {
+	if at, ok := c.read(path, previous); ok {
+		return at, nil
+	}
+
+	unlock, err := c.lock(ctx, path)
+	if err != nil {
+		if ctx.Err() != nil {
+			return AccessToken{}, ctx.Err()
+		}
+		return fetch(ctx)
+	}
+	defer unlock()
+
+	// Another process may have refreshed the token while we were waiting for the lock.
+	if at, ok := c.read(path, previous); ok {
+		return at, nil
+	}
+
+	at, err := fetch(ctx)
+	if err != nil {
+		return at, err
+	}
+	c.write(path, at)
+	return at, nil
+}
+
+// read returns the token cached in path if it can be used.
+// Expired tokens are left for write to replace: removing them without holding the lock could remove
+// the token another process just cached.
+func (c *FileTokenCache) read(path string, previous string) (AccessToken, bool) {
+	at := AccessToken{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &at) != nil {
+		return at, false
+	}
+	return at, at.Value != previous && !at.hasExpired() && !at.needsRefresh()
+}
+
+// write atomically replaces the token cached in path, so readers never see a partial file.
+func (c *FileTokenCache) write(path string, at AccessToken) error {
+	data, err := json.Marshal(at)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(c.Dir, ".token-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	// TempFile creates files with 0600 permissions, set them anyway in case the platform differs.
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// lock creates the lock file of path, waiting for other processes to release it or ctx to be done.
+// Locks older than tokenCacheStaleLock are removed, as their process most likely crashed.
+// The lock file holds a random owner, so releasing a lock removed as stale does not remove the lock of
+// another process, and it is touched every tokenCacheLockRefresh until released.
+func (c *FileTokenCache) lock(ctx context.Context, path string) (func(), error) {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return nil, err
+	}
+
+	owner, err := newLockOwner()
+	if err != nil {
+		return nil, err
+	}
+	lockPath := path + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			_, err = f.Write(owner)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lockPath)
+				return nil, err
+			}
+			return refreshLock(lockPath, owner), nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if fi, err := os.Stat(lockPath); err == nil && time.Since(fi.ModTime()) > tokenCacheStaleLock {
+			if stale, err := ioutil.ReadFile(lockPath); err == nil {
+				removeLock(lockPath, stale)
+			}
+			continue
+		}
+
+		t := time.NewTimer(tokenCacheLockRetry)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// newLockOwner returns a random value identifying the holder of a lock.
+func newLockOwner() ([]byte, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return []byte(hex.EncodeToString(b)), nil
+}
+
+// refreshLock touches the lock file held by owner every tokenCacheLockRefresh, and returns the function releasing it.
+func refreshLock(lockPath string, owner []byte) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		t := time.NewTicker(tokenCacheLockRefresh)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				if !ownsLock(lockPath, owner) {
+					return
+				}
+				now := time.Now()
+				os.Chtimes(lockPath, now, now)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		removeLock(lockPath, owner)
+	}
+}
+
+// ownsLock returns true if the lock file still holds owner.
+func ownsLock(lockPath string, owner []byte) bool {
+	data, err := ioutil.ReadFile(lockPath)
+	return err == nil && bytes.Equal(data, owner)
+}
+
+// removeLock removes the lock file only if it still holds owner, as it may have been taken over by another process.
+func removeLock(lockPath string, owner []byte) {
+	if ownsLock(lockPath, owner) {
+		os.Remove(lockPath)
+	}
+}
+
+// Clear removes every token of the cache, for instance after revoking the client credentials.
+func (c *FileTokenCache) Clear() error {
+	paths, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, p := range paths {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,46 @@
+package mnubo
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFileTokenCache_Lock(t *testing.T) {
+	c := NewFileTokenCache(t.TempDir())
+	path := c.path("https://rest.sandbox.mnubo.com", "CLIENT_ID", ScopeAll)
+
+	unlock, err := c.lock(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unable to lock: %+v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+	if _, err := c.lock(ctx, path); err != context.DeadlineExceeded {
+		t.Errorf("expecting lock to be held, got: %+v", err)
+	}
+	unlock()
+
+	// A lock left by a crashed process is removed once stale.
+	staleUnlock, err := c.lock(context.Background(), path)
+	if err != nil {
+		t.Fatalf("unable to lock: %+v", err)
+	}
+	old := time.Now().Add(-tokenCacheStaleLock * 2)
+	os.Chtimes(path+".lock", old, old)
+	unlock, err = c.lock(context.Background(), path)
+	if err != nil {
+		t.Fatalf("expecting stale lock to be removed, got: %+v", err)
+	}
+
+	// Releasing the stale lock does not remove the lock of its new owner.
+	staleUnlock()
+	if _, err := os.Stat(path + ".lock"); err != nil {
+		t.Errorf("expecting the lock to be kept, got: %+v", err)
+	}
+	unlock()
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("expecting the lock to be removed, got: %+v", err)
+	}
+}
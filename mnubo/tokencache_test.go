@@ -0,0 +1,116 @@
+package mnubo_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mnubo/smartobjects-go-client/mnubo"
+	"github.com/mnubo/smartobjects-go-client/mnubo/mnubotest"
+)
+
+func newTokenCacheClient(t *testing.T, srv *mnubotest.Server, dir string) *mnubo.Mnubo {
+	m, err := srv.NewClient(mnubo.WithTokenCache(mnubo.NewFileTokenCache(dir)))
+	if err != nil {
+		t.Fatalf("unable to create client: %+v", err)
+	}
+	return m
+}
+
+// tokenRequests returns the number of tokens requested to srv.
+func tokenRequests(srv *mnubotest.Server) int {
+	n := 0
+	for _, r := range srv.Requests() {
+		if r == "POST /oauth/token" {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFileTokenCache_SharedBetweenClients(t *testing.T) {
+	srv := mnubotest.NewServer()
+	defer srv.Close()
+	// Slow token requests, so the clients wait for each other.
+	srv.Inject(mnubotest.Fault{Path: "/oauth/token", Times: -1, Delay: time.Millisecond * 50})
+	dir := filepath.Join(t.TempDir(), "tokens")
+
+	// Each client stands for a process, they only share the directory.
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var results []mnubo.Dataset
+			errs[i] = newTokenCacheClient(t, srv, dir).Search.GetDatasets(&results)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("%d, client call failed: %+v", i, err)
+		}
+	}
+	if n := tokenRequests(srv); n != 1 {
+		t.Errorf("expecting a single token request, got: %d", n)
+	}
+
+	c := mnubo.NewFileTokenCache(dir)
+	fi, err := os.Stat(c.Path(srv.URL, srv.ClientID, mnubo.ScopeAll))
+	if err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expecting token file with 0600 permissions, got: %+v", err)
+	}
+	if c.Path(srv.URL, srv.ClientID, mnubo.ScopeRead) == c.Path(srv.URL, srv.ClientID, mnubo.ScopeAll) ||
+		c.Path(srv.URL, "OTHER_ID", mnubo.ScopeAll) == c.Path(srv.URL, srv.ClientID, mnubo.ScopeAll) ||
+		c.Path("https://other", srv.ClientID, mnubo.ScopeAll) == c.Path(srv.URL, srv.ClientID, mnubo.ScopeAll) {
+		t.Errorf("expecting one file per host, client id and scope")
+	}
+}
+
+func TestFileTokenCache_Invalidation(t *testing.T) {
+	srv := mnubotest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	c := mnubo.NewFileTokenCache(dir)
+	path := c.Path(srv.URL, srv.ClientID, mnubo.ScopeAll)
+
+	// An expired token is not used, and is left for the process refreshing it to replace.
+	if err := c.Write(path, mnubo.AccessToken{Value: "EXPIRED", ExpiresIn: 1000, ExpiresAt: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatalf("unable to write token: %+v", err)
+	}
+	if _, ok := c.Read(path, ""); ok {
+		t.Errorf("expecting the expired token not to be used")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expecting the expired token to be kept without the lock, got: %+v", err)
+	}
+	m := newTokenCacheClient(t, srv, dir)
+	var results []mnubo.Dataset
+	if err := m.Search.GetDatasets(&results); err != nil || m.CachedAccessToken("").Value == "EXPIRED" {
+		t.Errorf("expecting a new token, got: %s (%+v)", m.CachedAccessToken("").Value, err)
+	}
+	first := m.CachedAccessToken("").Value
+
+	// A rejected token is replaced, for the other processes as well.
+	srv.RevokeTokens()
+	if err := m.Search.GetDatasets(&results); err != nil || m.CachedAccessToken("").Value == first {
+		t.Errorf("expecting the rejected token to be replaced, got: %s (%+v)", m.CachedAccessToken("").Value, err)
+	}
+	if at, ok := c.Read(path, ""); !ok || at.Value != m.CachedAccessToken("").Value {
+		t.Errorf("expecting the new token to be cached, got: %s", at.Value)
+	}
+	if n := tokenRequests(srv); n != 2 {
+		t.Errorf("expecting 2 token requests, got: %d", n)
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Errorf("unable to clear the cache: %+v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expecting the token to be removed, got: %+v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package mnubo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+)
+
+// BatchResult is the result of each entity of a batch update of objects or owners.
+type BatchResult struct {
+	ID      string `json:"id"`
+	Result  string `json:"result"`
+	Message string `json:"message"`
+}
+
+// SendEvents is like Events.SendContext with typed events and results.
+// The reports are only returned when options.ReportResults is set.
+func SendEvents[T any](ctx context.Context, e EventsAPI, events []T, options SendEventsOptions) ([]SendEventsReport, error) {
+	var results []SendEventsReport
+	err := e.SendContext(ctx, events, options, &results)
+	return results, err
+}
+
+// SendEventsFromDevice is like Events.SendFromDeviceContext with typed events and results.
+// The reports are only returned when options.ReportResults is set.
+func SendEventsFromDevice[T any](ctx context.Context, e EventsAPI, deviceId string, events []T, options SendEventsOptions) ([]SendEventsReport, error) {
+	var results []SendEventsReport
+	err := e.SendFromDeviceContext(ctx, deviceId, events, options, &results)
+	return results, err
+}
+
+// CreateObject is like Objects.CreateContext with a typed object.
+func CreateObject[T any](ctx context.Context, o ObjectsAPI, object T) error {
+	var results interface{}
+	return o.CreateContext(ctx, object, &results)
+}
+
+// UpdateObjects is like Objects.UpdateContext with typed objects and results.
+func UpdateObjects[T any](ctx context.Context, o ObjectsAPI, objects []T) ([]BatchResult, error) {
+	var results []BatchResult
+	err := o.UpdateContext(ctx, objects, &results)
+	return results, err
+}
+
+// CreateOwner is like Owners.CreateContext with a typed owner.
+func CreateOwner[T any](ctx context.Context, o OwnersAPI, owner T) error {
+	var results interface{}
+	return o.CreateContext(ctx, owner, &results)
+}
+
+// UpdateOwners is like Owners.UpdateContext with typed owners and results.
+func UpdateOwners[T any](ctx context.Context, o OwnersAPI, owners []T) ([]BatchResult, error) {
+	var results []BatchResult
+	err := o.UpdateContext(ctx, owners, &results)
+	return results, err
+}
+
+// Query sends an MQL query and decodes each row into a T, like a struct whose JSON tags are the column labels.
+// The response is read into SearchResults before the rows are decoded, so the query is retried like any
+// other, see QueryRows to decode the rows as they are received.
+func Query[T any](ctx context.Context, s SearchAPI, mql interface{}) ([]T, error) {
+	var results SearchResults
+	if err := s.CreateBasicQueryContext(ctx, mql, &results); err != nil {
+		return nil, err
+	}
+	rows := make([]T, len(results.Rows))
+	for i, row := range results.Rows {
+		if err := decodeRow(results.Columns, row, &rows[i]); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// QueryRows is like Query but returns an iterator over the rows decoded as they are received, like Search.Rows.
+// Iteration stops after the first error. The query is not retried once the response is received, see SearchRows.
+func QueryRows[T any](ctx context.Context, s SearchAPI, mql interface{}) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		rows := &SearchRows{}
+		rows.OnRow = func(row []interface{}) error {
+			var r T
+			if err := decodeRow(rows.Columns, row, &r); err != nil {
+				return err
+			}
+			if !yield(r, nil) {
+				return ErrStopRows
+			}
+			return nil
+		}
+		if err := s.CreateBasicQueryContext(ctx, mql, rows); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// decodeRow decodes a search row into result, through a JSON object whose keys are the column labels.
+func decodeRow(columns []SearchResultsColumn, row []interface{}, result interface{}) error {
+	if len(row) != len(columns) {
+		return fmt.Errorf("invalid search results: %d columns, got a row of %d values", len(columns), len(row))
+	}
+	fields := make(map[string]interface{}, len(columns))
+	for i, c := range columns {
+		fields[c.Label] = row[i]
+	}
+	bytes, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, result)
+}
@@ -0,0 +1,118 @@
+package mnubo
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+type typedEvent struct {
+	XEventType string  `json:"x_event_type"`
+	Speed      float64 `json:"speed"`
+}
+
+type typedRow struct {
+	DeviceID string  `json:"x_device_id"`
+	Count    int     `json:"count"`
+	Average  float64 `json:"average"`
+}
+
+func TestSendEvents(t *testing.T) {
+	var received []typedEvent
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		if r.URL.Query().Get("report_results") != "true" {
+			t.Errorf("expecting the results to be reported: %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"event-1","result":"success","objectExists":true}]`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	reports, err := SendEvents(context.Background(), m.Events, []typedEvent{{XEventType: "speed", Speed: 12.5}}, SendEventsOptions{ReportResults: true})
+	if err != nil {
+		t.Fatalf("send failed: %+v", err)
+	}
+	if len(reports) != 1 || reports[0].ID != "event-1" || !reports[0].ObjectExists {
+		t.Errorf("unexpected reports: %+v", reports)
+	}
+	if len(received) != 1 || received[0].Speed != 12.5 {
+		t.Errorf("unexpected events: %+v", received)
+	}
+}
+
+func TestUpdateObjects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"device-1","result":"success"},{"id":"device-2","result":"error","message":"invalid"}]`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	results, err := UpdateObjects(context.Background(), m.Objects, []map[string]string{{"x_device_id": "device-1"}, {"x_device_id": "device-2"}})
+	if err != nil || len(results) != 2 || results[1].Result != "error" || results[1].Message != "invalid" {
+		t.Errorf("unexpected results: %+v (%+v)", results, err)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"columns":[{"label":"x_device_id","type":"TEXT"},{"label":"count","type":"LONG"},{"label":"average","type":"DOUBLE"}],` +
+			`"rows":[["device-1",1,1.5],["device-2",2,null]]}`))
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	rows, err := Query[typedRow](context.Background(), m.Search, map[string]string{})
+	if err != nil {
+		t.Fatalf("query failed: %+v", err)
+	}
+	expected := []typedRow{{"device-1", 1, 1.5}, {"device-2", 2, 0}}
+	if len(rows) != 2 || rows[0] != expected[0] || rows[1] != expected[1] {
+		t.Errorf("expecting: %+v, got: %+v", expected, rows)
+	}
+
+	var devices []string
+	for row, err := range QueryRows[typedRow](context.Background(), m.Search, map[string]string{}) {
+		if err != nil {
+			t.Fatalf("iteration failed: %+v", err)
+		}
+		devices = append(devices, row.DeviceID)
+		break
+	}
+	if len(devices) != 1 || devices[0] != "device-1" {
+		t.Errorf("expecting iteration to stop after the first row, got: %+v", devices)
+	}
+
+	if _, err := Query[struct{ Count string }](context.Background(), m.Search, map[string]string{}); err == nil {
+		t.Errorf("expecting rows not matching the type to fail the query")
+	}
+}
+
+func TestQuery_RetryTruncated(t *testing.T) {
+	body := gzipBytes(`{"columns":[{"label":"x_device_id","type":"TEXT"},{"label":"count","type":"LONG"}],"rows":[["device-1",1],["device-2",2]]}`)
+	var attempts int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.Write(body[:len(body)/2])
+			return
+		}
+		w.Write(body)
+	}))
+	defer ts.Close()
+	m := NewClientWithToken("TOKEN", ts.URL)
+
+	rows, err := Query[typedRow](context.Background(), m.Search, map[string]string{})
+	if err != nil || len(rows) != 2 || rows[1].DeviceID != "device-2" || attempts != 2 {
+		t.Errorf("expecting the truncated response to be retried, got %d attempts: %+v (%+v)", attempts, rows, err)
+	}
+}